@@ -0,0 +1,185 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FindingKind is the kind of a problem found by Analyze
+type FindingKind string
+
+const (
+	// Unreachable states cannot be reached from the initial state
+	Unreachable FindingKind = "UNREACHABLE"
+	// DeadEnd states are not final and have no outgoing transitions
+	DeadEnd FindingKind = "DEAD_END"
+	// Sink states are not final, have outgoing transitions, but cannot reach any final state
+	Sink FindingKind = "SINK"
+	// Nondeterministic transitions share their source state and action but not their destination
+	Nondeterministic FindingKind = "NONDETERMINISTIC"
+	// Orphan states are not the initial state, have no sub states and no transitions from or to them
+	Orphan FindingKind = "ORPHAN"
+	// UnusableAction actions only have transitions from unreachable states
+	UnusableAction FindingKind = "UNUSABLE_ACTION"
+)
+
+var findingOrder = map[FindingKind]int{
+	Unreachable:      0,
+	DeadEnd:          1,
+	Sink:             2,
+	Nondeterministic: 3,
+	Orphan:           4,
+	UnusableAction:   5,
+}
+
+// Finding is a problem of a fsm definition found by Analyze
+type Finding struct {
+	Kind   FindingKind
+	State  string
+	Action string
+	// Targets are the destinations of nondeterministic transitions
+	Targets []string
+}
+
+func (f Finding) String() string {
+	switch f.Kind {
+	case Nondeterministic:
+		return fmt.Sprintf("%v: %v from %v leads to %v", f.Kind, f.Action, f.State, strings.Join(f.Targets, ", "))
+	case UnusableAction:
+		return fmt.Sprintf("%v: %v", f.Kind, f.Action)
+	}
+	return fmt.Sprintf("%v: %v", f.Kind, f.State)
+}
+
+// Analyze reports the problems of the fsm definition
+// Transitions of a parent state count as transitions of its sub states
+// Unreachable states and unusable actions are only reported when the fsm has an initial state,
+// sink states only when it has final states
+func (f *FSM) Analyze() []Finding {
+	var findings []Finding
+	outgoing := make(map[string][]string)
+	for _, s := range f.stateNames() {
+		for _, p := range f.path(s) {
+			for _, adj := range f.adj {
+				if adj.From == p {
+					outgoing[s] = append(outgoing[s], adj.To)
+				}
+			}
+		}
+	}
+
+	var reachable map[string]bool
+	if f.initial != "" {
+		reachable = make(map[string]bool)
+		queue := []string{f.initial}
+		for len(queue) > 0 {
+			s := queue[0]
+			queue = queue[1:]
+			if reachable[s] {
+				continue
+			}
+			// being in a state means being in all its ancestors
+			for _, p := range f.path(s) {
+				reachable[p] = true
+			}
+			queue = append(queue, outgoing[s]...)
+		}
+		for _, s := range f.stateNames() {
+			if !reachable[s] {
+				findings = append(findings, Finding{Kind: Unreachable, State: s})
+			}
+		}
+	}
+
+	for _, s := range f.stateNames() {
+		if !f.final[s] && len(outgoing[s]) == 0 {
+			findings = append(findings, Finding{Kind: DeadEnd, State: s})
+		}
+	}
+
+	if len(f.final) > 0 {
+		finishes := make(map[string]bool)
+		for changed := true; changed; {
+			changed = false
+			for _, s := range f.stateNames() {
+				if finishes[s] {
+					continue
+				}
+				ok := f.final[s]
+				for _, des := range outgoing[s] {
+					ok = ok || finishes[des]
+				}
+				if ok {
+					finishes[s] = true
+					changed = true
+				}
+			}
+		}
+		for _, s := range f.stateNames() {
+			if !finishes[s] && len(outgoing[s]) > 0 {
+				findings = append(findings, Finding{Kind: Sink, State: s})
+			}
+		}
+	}
+
+	type fromAction struct {
+		from   string
+		action string
+	}
+	targets := make(map[fromAction][]string)
+	var keys []fromAction
+	for _, adj := range f.adj {
+		k := fromAction{from: adj.From, action: adj.Action}
+		if targets[k] == nil {
+			keys = append(keys, k)
+		}
+		targets[k] = append(targets[k], adj.To)
+	}
+	for _, k := range keys {
+		if len(targets[k]) > 1 {
+			findings = append(findings, Finding{Kind: Nondeterministic, State: k.from, Action: k.action, Targets: targets[k]})
+		}
+	}
+
+	connected := make(map[string]bool)
+	for _, adj := range f.adj {
+		connected[adj.To] = true
+	}
+	for _, parent := range f.parents {
+		connected[parent] = true
+	}
+	for _, s := range f.stateNames() {
+		if !connected[s] && len(outgoing[s]) == 0 && s != f.initial {
+			findings = append(findings, Finding{Kind: Orphan, State: s})
+		}
+	}
+
+	if reachable != nil {
+		usable := make(map[string]bool)
+		var actions []string
+		for _, adj := range f.adj {
+			if _, ok := usable[adj.Action]; !ok {
+				actions = append(actions, adj.Action)
+			}
+			usable[adj.Action] = usable[adj.Action] || reachable[adj.From]
+		}
+		for _, a := range actions {
+			if !usable[a] {
+				findings = append(findings, Finding{Kind: UnusableAction, Action: a})
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Kind != b.Kind {
+			return findingOrder[a.Kind] < findingOrder[b.Kind]
+		}
+		if a.State != b.State {
+			return a.State < b.State
+		}
+		return a.Action < b.Action
+	})
+	return findings
+}
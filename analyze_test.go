@@ -0,0 +1,98 @@
+package fsm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	f, err := New("Order", [][3]string{
+		{"NEW", "PAID", "PAY"},
+		{"NEW", "CANCELED", "PAY"},
+		{"PAID", "SHIPPED", "SHIP"},
+		{"PAID", "LOOP", "WAIT"},
+		{"LOOP", "LOOP", "WAIT"},
+		{"LOST", "SHIPPED", "FIND"},
+		{"HELD", "HELD", "HOLD"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddState("DRAFT"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddFinal("SHIPPED"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Init("NEW"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Finding{
+		{Kind: Unreachable, State: "DRAFT"},
+		{Kind: Unreachable, State: "HELD"},
+		{Kind: Unreachable, State: "LOST"},
+		{Kind: DeadEnd, State: "CANCELED"},
+		{Kind: DeadEnd, State: "DRAFT"},
+		{Kind: Sink, State: "HELD"},
+		{Kind: Sink, State: "LOOP"},
+		{Kind: Nondeterministic, State: "NEW", Action: "PAY", Targets: []string{"PAID", "CANCELED"}},
+		{Kind: Orphan, State: "DRAFT"},
+		{Kind: UnusableAction, Action: "FIND"},
+		{Kind: UnusableAction, Action: "HOLD"},
+	}
+	if got := f.Analyze(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong findings: %v", got)
+	}
+}
+
+func TestAnalyzeSubStates(t *testing.T) {
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tenant.State
+	// GOLD inherits SUSPEND from ACTIVE, ACTIVE is reachable through its sub states
+	if got := f.Analyze(); len(got) != 0 {
+		t.Errorf("tenant should have no findings, got %v", got)
+	}
+}
+
+func TestAnalyzeInheritedTrans(t *testing.T) {
+	f := NewFSM("Tenant")
+	for _, s := range []string{"ACTIVE", "SUSPENDED"} {
+		if err := f.AddState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range []string{"TRIAL", "BASIC"} {
+		if err := f.AddSubState("ACTIVE", s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.AddTrans("ACTIVE", "SUSPENDED", "SUSPEND"); err != nil {
+		t.Fatal(err)
+	}
+	// BASIC has no transition of its own but it inherits SUSPEND from ACTIVE
+	want := []Finding{{Kind: DeadEnd, State: "SUSPENDED"}}
+	if got := f.Analyze(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong findings: %v", got)
+	}
+}
+
+func TestAnalyzeNoInitial(t *testing.T) {
+	f, err := New("Order", [][3]string{
+		{"NEW", "PAID", "PAY"},
+		{"LOST", "PAID", "FIND"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{{Kind: DeadEnd, State: "PAID"}}
+	if got := f.Analyze(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong findings: %v", got)
+	}
+	if got := want[0].String(); got != "DEAD_END: PAID" {
+		t.Errorf("wrong finding string: %v", got)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/lemenendez/fsm"
+)
+
+var errDiffer = errors.New("definitions differ")
+
+// diff prints the differences between two definitions, it fails when there is any
+// Removed lines start with -, added lines with + and changed lines with ~
+func diff(args []string, stdout io.Writer) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	a, err := fsm.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := fsm.LoadFile(args[1])
+	if err != nil {
+		return err
+	}
+	lines := changes(a, b)
+	for _, line := range lines {
+		fmt.Fprintln(stdout, line)
+	}
+	if len(lines) > 0 {
+		return errDiffer
+	}
+	return nil
+}
+
+// changes returns the differences between two definitions
+func changes(a *fsm.FSM, b *fsm.FSM) []string {
+	var lines []string
+	changed := func(what string, old string, new string) {
+		if old != new {
+			lines = append(lines, fmt.Sprintf("~ %v %q -> %q", what, old, new))
+		}
+	}
+	changed("name", a.Name, b.Name)
+	changed("initial", a.GetInitial(), b.GetInitial())
+
+	lines = append(lines, setDiff("state", a.States(), b.States())...)
+	lines = append(lines, setDiff("final", finals(a), finals(b))...)
+	for _, s := range b.States() {
+		if contains(a.States(), s) {
+			changed("parent of "+s, a.GetParent(s), b.GetParent(s))
+		}
+	}
+	lines = append(lines, setDiff("transition", transitions(a), transitions(b))...)
+	return lines
+}
+
+// setDiff returns the removed and added items, sorted
+func setDiff(what string, a []string, b []string) []string {
+	var lines []string
+	for _, s := range a {
+		if !contains(b, s) {
+			lines = append(lines, fmt.Sprintf("- %v %v", what, s))
+		}
+	}
+	for _, s := range b {
+		if !contains(a, s) {
+			lines = append(lines, fmt.Sprintf("+ %v %v", what, s))
+		}
+	}
+	return lines
+}
+
+func finals(f *fsm.FSM) []string {
+	var names []string
+	for _, s := range f.States() {
+		if f.IsFinal(s) {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+func transitions(f *fsm.FSM) []string {
+	var names []string
+	for _, t := range f.Transitions() {
+		names = append(names, fmt.Sprintf("%v -> %v (%v)", t[0], t[1], t[2]))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
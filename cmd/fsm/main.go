@@ -0,0 +1,199 @@
+// fsm works with fsm definition files from the command line.
+//
+// Usage:
+//
+//	fsm validate definition
+//	fsm trans definition
+//	fsm render dot|mermaid|plantuml|scxml|yaml|toml|json definition
+//	fsm run [-from state] definition < actions
+//	fsm diff old new
+//
+// Definition files are read with fsm.LoadFile.
+// run reads one action per line, optionally followed by its destination state,
+// it prints every transition executed and the resulting state.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/lemenendez/fsm"
+)
+
+const usage = `usage:
+	fsm validate definition
+	fsm trans definition
+	fsm render dot|mermaid|plantuml|scxml|yaml|toml|json definition
+	fsm run [-from state] definition < actions
+	fsm diff old new
+`
+
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		if err == errUsage {
+			fmt.Fprint(os.Stderr, usage)
+		}
+		fmt.Fprintln(os.Stderr, "fsm:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command given by the arguments
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "validate":
+		return validate(args, stdout)
+	case "trans":
+		return trans(args, stdout)
+	case "render":
+		return render(args, stdout)
+	case "run":
+		return runActions(args, stdin, stdout)
+	case "diff":
+		return diff(args, stdout)
+	}
+	return errUsage
+}
+
+// load loads the only definition file of the arguments
+func load(args []string) (*fsm.FSM, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	return fsm.LoadFile(args[0])
+}
+
+// validate prints the problems found by fsm.Analyze, it fails when there is any
+func validate(args []string, stdout io.Writer) error {
+	f, err := load(args)
+	if err != nil {
+		return err
+	}
+	findings := f.Analyze()
+	for _, finding := range findings {
+		fmt.Fprintln(stdout, finding)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%v: %v problems found", args[0], len(findings))
+	}
+	fmt.Fprintf(stdout, "%v: ok\n", args[0])
+	return nil
+}
+
+// trans prints the transitions like fsm.GetTrans
+func trans(args []string, stdout io.Writer) error {
+	f, err := load(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(stdout, f.GetTrans())
+	return nil
+}
+
+// render prints the definition in the given format
+func render(args []string, stdout io.Writer) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	f, err := load(args[1:])
+	if err != nil {
+		return err
+	}
+	var out string
+	switch args[0] {
+	case "dot":
+		out = f.DOT(fsm.DOTOptions{})
+	case "mermaid":
+		out = f.Mermaid()
+	case "plantuml":
+		out = f.PlantUML(fsm.PlantUMLOptions{})
+	case "yaml":
+		out = f.YAML()
+	case "toml":
+		out = f.TOML()
+	case "scxml":
+		data, err := f.SCXML()
+		if err != nil {
+			return err
+		}
+		out = string(data)
+	case "json":
+		data, err := json.MarshalIndent(f, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = string(data) + "\n"
+	default:
+		return fmt.Errorf("unknown format %v", args[0])
+	}
+	fmt.Fprint(stdout, out)
+	return nil
+}
+
+// runActions runs the actions read from stdin, it stops at the first one not allowed
+func runActions(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	from := flags.String("from", "", "state to start from, the initial state by default")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	f, err := load(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *from != "" {
+		if err = f.Init(*from); err != nil {
+			return fmt.Errorf("%v: %w", *from, err)
+		}
+	}
+	if f.GetState() == "" {
+		return errors.New("no initial state, use -from")
+	}
+
+	callback := func(previous string, new string, action string) {
+		fmt.Fprintf(stdout, "%v -> %v (%v)\n", previous, new, action)
+	}
+	scanner := bufio.NewScanner(stdin)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 1:
+			err = f.Fire(fields[0], callback)
+		case 2:
+			err = f.Exec(fields[0], fields[1], callback)
+		default:
+			err = errors.New("expected an action and an optional state")
+		}
+		if err != nil {
+			fmt.Fprintln(stdout, f.GetState())
+			return fmt.Errorf("line %v: %v from %v: %w", line, fields[0], f.GetState(), err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if f.Terminated() {
+		fmt.Fprintf(stdout, "%v (final)\n", f.GetState())
+		return nil
+	}
+	fmt.Fprintln(stdout, f.GetState())
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lemenendez/fsm"
+)
+
+const planYAML = `name: SAAS Account State
+initial: TRIAL
+final: [CANCELED]
+states:
+  TRIAL:
+  BASIC:
+  PREMIUM:
+  CANCELED:
+transitions:
+  - {from: TRIAL, to: BASIC, action: UPGRATE}
+  - {from: TRIAL, to: PREMIUM, action: UPGRATE}
+  - {from: BASIC, to: CANCELED, action: CANCEL}
+  - {from: PREMIUM, to: CANCELED, action: CANCEL}
+`
+
+const plan2YAML = `name: SAAS Account State
+initial: TRIAL
+final: [CANCELED]
+states:
+  TRIAL:
+  BASIC:
+  GOLD:
+  CANCELED:
+transitions:
+  - {from: TRIAL, to: BASIC, action: UPGRATE}
+  - {from: BASIC, to: GOLD, action: UPGRATE}
+  - {from: BASIC, to: CANCELED, action: CANCEL}
+  - {from: GOLD, to: CANCELED, action: CANCEL}
+`
+
+// writeFiles writes the given files into a temporary directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "fsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestValidate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"plan.yaml": planYAML, "plan2.yaml": plan2YAML})
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	if err := run([]string{"validate", filepath.Join(dir, "plan.yaml")}, nil, &out); err == nil {
+		t.Errorf("plan should not be valid")
+	}
+	if !strings.Contains(out.String(), "NONDETERMINISTIC: UPGRATE from TRIAL leads to BASIC, PREMIUM") {
+		t.Errorf("wrong output: %v", out.String())
+	}
+	out.Reset()
+	if err := run([]string{"validate", filepath.Join(dir, "plan2.yaml")}, nil, &out); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func TestTransRender(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"plan.yaml": planYAML})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plan.yaml")
+
+	var out bytes.Buffer
+	if err := run([]string{"trans", path}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Transitions SAAS Account State:\nTRIAL (BASIC) -> (UPGRATE)\n") {
+		t.Errorf("wrong output: %v", out.String())
+	}
+	for format, want := range map[string]string{
+		"dot":      "digraph",
+		"mermaid":  "stateDiagram-v2",
+		"plantuml": "@startuml",
+		"yaml":     "initial: TRIAL",
+		"json":     `"initial": "TRIAL"`,
+	} {
+		out.Reset()
+		if err := run([]string{"render", format, path}, nil, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%v output should contain %v, got %v", format, want, out.String())
+		}
+	}
+	if err := run([]string{"render", "png", path}, nil, &out); err == nil {
+		t.Errorf("should errored unknown format")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"plan.yaml": planYAML})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plan.yaml")
+
+	var out bytes.Buffer
+	stdin := strings.NewReader("# upgrade then cancel\nUPGRATE BASIC\n\nCANCEL\n")
+	if err := run([]string{"run", path}, stdin, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "TRIAL -> BASIC (UPGRATE)\nBASIC -> CANCELED (CANCEL)\nCANCELED (final)\n"
+	if out.String() != want {
+		t.Errorf("wrong output: %q", out.String())
+	}
+
+	out.Reset()
+	err := run([]string{"run", "-from", "PREMIUM", path}, strings.NewReader("UPGRATE\n"), &out)
+	if !errors.Is(err, fsm.ErrExecNotAllowed) || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("should errored exec not allowed at line 1, got %v", err)
+	}
+	if out.String() != "PREMIUM\n" {
+		t.Errorf("wrong output: %q", out.String())
+	}
+	if err = run([]string{"run", "-from", "GOLD", path}, strings.NewReader(""), &out); !errors.Is(err, fsm.ErrStateNotFound) {
+		t.Errorf("should errored state not found, got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"plan.yaml": planYAML, "plan2.yaml": plan2YAML})
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	err := run([]string{"diff", filepath.Join(dir, "plan.yaml"), filepath.Join(dir, "plan2.yaml")}, nil, &out)
+	if err != errDiffer {
+		t.Errorf("should errored differ, got %v", err)
+	}
+	want := `- state PREMIUM
++ state GOLD
+- transition PREMIUM -> CANCELED (CANCEL)
+- transition TRIAL -> PREMIUM (UPGRATE)
++ transition BASIC -> GOLD (UPGRATE)
++ transition GOLD -> CANCELED (CANCEL)
+`
+	if out.String() != want {
+		t.Errorf("wrong diff:\n%v", out.String())
+	}
+
+	out.Reset()
+	if err = run([]string{"diff", filepath.Join(dir, "plan.yaml"), filepath.Join(dir, "plan.yaml")}, nil, &out); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"explode"}, {"trans"}, {"diff", "a.yaml"}} {
+		if err := run(args, nil, ioutil.Discard); err != errUsage {
+			t.Errorf("%v should errored usage, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lemenendez/fsm"
+)
+
+// options are the options of the generated file
+type options struct {
+	Package string
+	// Type is the name of the fsm type, the States and Actions types are prefixed with it
+	Type string
+	// Source is the definition file name written in the header
+	Source string
+}
+
+// reserved are the methods of the generated fsm type an action cannot be named after
+var reserved = map[string]bool{
+	"FSM":   true,
+	"State": true,
+	"Move":  true,
+}
+
+// generate returns the formatted Go source of the typed fsm
+func generate(f *fsm.FSM, opts options) ([]byte, error) {
+	if f.GetInitial() == "" {
+		return nil, fmt.Errorf("%v has no initial state", f.Name)
+	}
+	if opts.Type == "" {
+		opts.Type = typeName(f.Name)
+	}
+	if !token.IsIdentifier(opts.Type) || !token.IsExported(opts.Type) {
+		return nil, fmt.Errorf("invalid type name %q", opts.Type)
+	}
+	if !token.IsIdentifier(opts.Package) {
+		return nil, fmt.Errorf("invalid package name %q", opts.Package)
+	}
+	for _, action := range f.Actions() {
+		if name := camel(action); reserved[name] {
+			return nil, fmt.Errorf("action %v conflicts with the %v method", action, name)
+		}
+	}
+	definition, err := definitionJSON(f)
+	if err != nil {
+		return nil, err
+	}
+
+	t := opts.Type
+	recv := strings.ToLower(t[:1])
+	state, action := t+"State", t+"Action"
+	def := strings.ToLower(t[:1]) + t[1:] + "Definition"
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by fsmgen from %v. DO NOT EDIT.\n\n", opts.Source)
+	fmt.Fprintf(&b, "package %v\n\n", opts.Package)
+	fmt.Fprintf(&b, "import (\n\"encoding/json\"\n\n\"github.com/lemenendez/fsm\"\n)\n\n")
+
+	fmt.Fprintf(&b, "// %v is a state of the %v fsm\n", state, f.Name)
+	fmt.Fprintf(&b, "type %v string\n\n", state)
+	fmt.Fprintf(&b, "// The states of the %v fsm\n", f.Name)
+	fmt.Fprintf(&b, "const (\n")
+	for _, s := range f.States() {
+		fmt.Fprintf(&b, "%v%v %v = %q\n", state, camel(s), state, s)
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	fmt.Fprintf(&b, "// %v is an action of the %v fsm\n", action, f.Name)
+	fmt.Fprintf(&b, "type %v string\n\n", action)
+	fmt.Fprintf(&b, "// The actions of the %v fsm\n", f.Name)
+	fmt.Fprintf(&b, "const (\n")
+	for _, a := range f.Actions() {
+		fmt.Fprintf(&b, "%v%v %v = %q\n", action, camel(a), action, a)
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	fmt.Fprintf(&b, "// %v is the JSON definition of the %v fsm\n", def, f.Name)
+	fmt.Fprintf(&b, "const %v = %v\n\n", def, quote(string(definition)))
+
+	fmt.Fprintf(&b, "// %v is the %v fsm with typed states and actions\n", t, f.Name)
+	fmt.Fprintf(&b, "type %v struct {\nfsm *fsm.FSM\n}\n\n", t)
+	fmt.Fprintf(&b, "// New%v creates the %v fsm at its initial state\n", t, f.Name)
+	fmt.Fprintf(&b, "func New%v() (*%v, error) {\n", t, t)
+	fmt.Fprintf(&b, "f := fsm.NewFSM(\"\")\n")
+	fmt.Fprintf(&b, "if err := json.Unmarshal([]byte(%v), f); err != nil {\nreturn nil, err\n}\n", def)
+	fmt.Fprintf(&b, "if err := f.Init(string(%v%v)); err != nil {\nreturn nil, err\n}\n", state, camel(f.GetInitial()))
+	fmt.Fprintf(&b, "return &%v{fsm: f}, nil\n}\n\n", t)
+
+	fmt.Fprintf(&b, "// FSM gets the underlying fsm\n")
+	fmt.Fprintf(&b, "func (%v *%v) FSM() *fsm.FSM {\nreturn %v.fsm\n}\n\n", recv, t, recv)
+	fmt.Fprintf(&b, "// State gets the current state\n")
+	fmt.Fprintf(&b, "func (%v *%v) State() %v {\nreturn %v(%v.fsm.GetState())\n}\n\n", recv, t, state, state, recv)
+	fmt.Fprintf(&b, "// Move moves the fsm to the given state using the given action\n")
+	fmt.Fprintf(&b, "func (%v *%v) Move(action %v, des %v) error {\nreturn %v.fsm.Exec(string(action), string(des), nil)\n}\n", recv, t, action, state, recv)
+	for _, a := range f.Actions() {
+		fmt.Fprintf(&b, "\n// %v fires the %v action from the current state\n", camel(a), a)
+		fmt.Fprintf(&b, "func (%v *%v) %v() error {\nreturn %v.fsm.Fire(string(%v%v), nil)\n}\n", recv, t, camel(a), recv, action, camel(a))
+	}
+	return format.Source(b.Bytes())
+}
+
+// definitionJSON returns the JSON representation of the fsm without its current state, entered times and history
+func definitionJSON(f *fsm.FSM) ([]byte, error) {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	var definition map[string]json.RawMessage
+	if err = json.Unmarshal(b, &definition); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"current", "entered", "history"} {
+		delete(definition, key)
+	}
+	return json.MarshalIndent(definition, "", "\t")
+}
+
+// camel converts a state or action name like SUSPEND_ALL to SuspendAll
+func camel(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(part[:1])
+		b.WriteString(strings.ToLower(part[1:]))
+	}
+	return b.String()
+}
+
+// typeName converts a fsm name like "SAAS Account State" to SaasAccountState
+func typeName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]))
+		b.WriteString(strings.ToLower(word[1:]))
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter(rune(s[0])) {
+		s = "FSM" + s
+	}
+	return s
+}
+
+// quote writes the string as a raw string literal when possible
+func quote(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lemenendez/fsm"
+)
+
+const planYAML = `name: SAAS Account State
+initial: TRIAL
+states:
+  TRIAL:
+  BASIC:
+  PREMIUM:
+transitions:
+  - {from: TRIAL, to: BASIC, action: UPGRATE}
+  - {from: BASIC, to: PREMIUM, action: UPGRATE}
+  - {from: PREMIUM, to: BASIC, action: DOWN_GRATE}
+`
+
+func TestGenerate(t *testing.T) {
+	f, err := fsm.ParseYAML(strings.NewReader(planYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the runtime data of the fsm is not part of the definition
+	f.EnableHistory()
+	if err = f.Fire("UPGRATE", nil); err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(f, options{Package: "plans", Source: "plan.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "plan_fsm.go", src, 0)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	if file.Name.Name != "plans" {
+		t.Errorf("package should be plans, got %v", file.Name.Name)
+	}
+	for _, want := range []string{
+		"// Code generated by fsmgen from plan.yaml. DO NOT EDIT.",
+		"type SaasAccountStateState string",
+		`SaasAccountStateStateTrial   SaasAccountStateState = "TRIAL"`,
+		`SaasAccountStateActionDownGrate SaasAccountStateAction = "DOWN_GRATE"`,
+		"func NewSaasAccountState() (*SaasAccountState, error) {",
+		"func (s *SaasAccountState) Move(action SaasAccountStateAction, des SaasAccountStateState) error {",
+		"func (s *SaasAccountState) DownGrate() error {",
+		`"initial": "TRIAL"`,
+		"if err := f.Init(string(SaasAccountStateStateTrial)); err != nil {",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("source should contain %q:\n%s", want, src)
+		}
+	}
+	for _, runtime := range []string{`"current"`, `"entered"`, `"history"`} {
+		if bytes.Contains(src, []byte(runtime)) {
+			t.Errorf("the definition should not contain %v\n%s", runtime, src)
+		}
+	}
+}
+
+// planMain uses the generated Plan type
+const planMain = `package main
+
+import "fmt"
+
+func main() {
+	p, err := NewPlan()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(p.State())
+	if err = p.Upgrate(); err != nil {
+		panic(err)
+	}
+	fmt.Print(" ", p.State())
+	if err = p.Move(PlanActionUpgrate, PlanStatePremium); err != nil {
+		panic(err)
+	}
+	fmt.Print(" ", p.State())
+}
+`
+
+func TestGenerateRun(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsm.ParseYAML(strings.NewReader(planYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(f, options{Package: "main", Type: "Plan", Source: "plan.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "fsmgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"go.mod":      "module plans\n\ngo 1.13\n\nrequire github.com/lemenendez/fsm v0.0.0\n\nreplace github.com/lemenendez/fsm => " + root + "\n",
+		"plan_fsm.go": string(src),
+		"main.go":     planMain,
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated code should build and run: %v\n%s\n%s", err, out, src)
+	}
+	if got := string(out); got != "TRIAL BASIC PREMIUM" {
+		t.Errorf("wrong states: %v", got)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	f, err := fsm.New("Plan", [][3]string{{"TRIAL", "BASIC", "UPGRATE"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = generate(f, options{Package: "plans"}); err == nil {
+		t.Errorf("should errored without initial state")
+	}
+	if err = f.Init("TRIAL"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = generate(f, options{Package: "plans", Type: "plan"}); err == nil {
+		t.Errorf("should errored unexported type")
+	}
+	if err = f.AddTrans("BASIC", "TRIAL", "MOVE"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = generate(f, options{Package: "plans"}); err == nil {
+		t.Errorf("should errored MOVE action")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsmgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "plan.yaml")
+	if err = ioutil.WriteFile(in, []byte(planYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	if err = run([]string{"-pkg", "plans", "-type", "Plan", in}, &stderr); err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	src, err := ioutil.ReadFile(filepath.Join(dir, "plan_fsm.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(src, []byte("func (p *Plan) Upgrate() error {")) {
+		t.Errorf("wrong source:\n%s", src)
+	}
+	if err = run([]string{"-pkg", "plans"}, &stderr); err == nil {
+		t.Errorf("should errored without definition file")
+	}
+}
+
+func TestNames(t *testing.T) {
+	for name, want := range map[string]string{
+		"UPGRATE":     "Upgrate",
+		"SUSPEND_ALL": "SuspendAll",
+	} {
+		if got := camel(name); got != want {
+			t.Errorf("wrong camel name for %v: %v", name, got)
+		}
+	}
+	for name, want := range map[string]string{
+		"SAAS Account State V1.0": "SaasAccountStateV10",
+		"2 phase":                 "FSM2Phase",
+	} {
+		if got := typeName(name); got != want {
+			t.Errorf("wrong type name for %v: %v", name, got)
+		}
+	}
+}
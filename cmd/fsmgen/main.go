@@ -0,0 +1,71 @@
+// fsmgen generates typed states, actions and helpers for a fsm definition file.
+//
+// Usage:
+//
+//	//go:generate go run github.com/lemenendez/fsm/cmd/fsmgen -type Plan plan.yaml
+//
+// The definition is read with fsm.LoadFile, it must have an initial state.
+// The generated file defines the PlanState and PlanAction types and their constants,
+// the Plan type, its NewPlan constructor and a method per action.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lemenendez/fsm"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "fsmgen:", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the file described by the command line arguments
+func run(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("fsmgen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	typeName := flags.String("type", "", "name of the generated fsm type, derived from the fsm name by default")
+	out := flags.String("out", "", "output file, the definition file name with a _fsm.go suffix by default")
+	pkg := flags.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated file, $GOPACKAGE by default")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: fsmgen [flags] definition")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected one definition file")
+	}
+	in := flags.Arg(0)
+	if *pkg == "" {
+		return errors.New("unknown package, use -pkg")
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(in, filepath.Ext(in)) + "_fsm.go"
+	}
+
+	f, err := fsm.LoadFile(in)
+	if err != nil {
+		return err
+	}
+	src, err := generate(f, options{
+		Package: *pkg,
+		Type:    *typeName,
+		Source:  filepath.Base(in),
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(*out, src, 0644)
+}
@@ -0,0 +1,126 @@
+package fsm
+
+import (
+	"context"
+	"time"
+)
+
+// InitContext is like Init but it does not change the current state
+// when the context is done, in that case it returns ctx.Err()
+func (f *FSM) InitContext(ctx context.Context, state string) error {
+	if ok := f.states[state]; !ok {
+		return ErrStateNotFound
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.initial = state
+	f.reset(state)
+	return nil
+}
+
+// reset set the current state without running any hook, the states of the active path are entered now
+func (f *FSM) reset(state string) {
+	f.current = state
+	f.entered = make(map[string]time.Time)
+	now := f.now()
+	for _, s := range f.path(state) {
+		f.entered[s] = now
+	}
+}
+
+// ExecContext is like Exec but the hooks and the callback receive the context
+// The context is checked before the guards and again before the hooks,
+// when it is done the fsm does not move and ctx.Err() is returned
+func (f *FSM) ExecContext(ctx context.Context, action string, des string, callback ContextHook) error {
+	if err := f.check(ctx, action, des); err != nil {
+		return err
+	}
+	f.move(ctx, f.now(), action, des, callback)
+	return nil
+}
+
+// check validates the fsm can move to the given state using the given action
+func (f *FSM) check(ctx context.Context, action string, des string) error {
+	if !f.isInternal {
+		if f.state.current != ready {
+			return ErrNotReady
+		}
+	}
+	if ok := f.states[des]; !ok {
+		return ErrStateNotFound
+	}
+	t, ok := f.lookupTrans(f.current, des, action)
+	if !ok {
+		return ErrExecNotAllowed
+	}
+	if err := f.canLeave(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.checkGuards(t); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// move moves the fsm to the given state calling the hooks, it must be checked first
+// at is the time the new state is entered
+func (f *FSM) move(ctx context.Context, at time.Time, action string, des string, callback ContextHook) {
+	previous := f.current
+	exit, enter := f.exitEnter(previous, des)
+	runHooks(ctx, f.hooks.beforeAll, previous, des, action)
+	runHooks(ctx, f.hooks.before[action], previous, des, action)
+	for _, s := range exit {
+		runHooks(ctx, f.hooks.exit[s], previous, des, action)
+	}
+	f.step(at, des)
+	f.record(ctx, at, previous, des, action)
+	for _, s := range enter {
+		runHooks(ctx, f.hooks.enter[s], previous, des, action)
+	}
+	runHooks(ctx, f.hooks.after[action], previous, des, action)
+	runHooks(ctx, f.hooks.afterAll, previous, des, action)
+	if callback != nil {
+		callback(ctx, previous, f.current, action)
+	}
+}
+
+// step moves the fsm to the given state without calling any hook or recording it, it must be checked first
+// at is the time the new states of the active path are entered
+func (f *FSM) step(at time.Time, des string) {
+	exit, enter := f.exitEnter(f.current, des)
+	for _, s := range exit {
+		delete(f.entered, s)
+	}
+	f.current = des
+	if f.entered == nil {
+		f.entered = make(map[string]time.Time)
+	}
+	for _, s := range enter {
+		f.entered[s] = at
+	}
+}
+
+// restorer returns a function that sets the fsm back to its current state, entered times and history
+func (f *FSM) restorer() func() {
+	current, history := f.current, f.history
+	entered := make(map[string]time.Time)
+	for s, t := range f.entered {
+		entered[s] = t
+	}
+	return func() {
+		f.current, f.entered, f.history = current, entered, history
+	}
+}
+
+// FireContext is like Fire but the hooks and the callback receive the context
+func (f *FSM) FireContext(ctx context.Context, action string, callback ContextHook) error {
+	des, err := f.resolve(action)
+	if err != nil {
+		return err
+	}
+	return f.ExecContext(ctx, action, des, callback)
+}
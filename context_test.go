@@ -0,0 +1,112 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+type ctxKey string
+
+func TestExecContextValues(t *testing.T) {
+	f, err := New("Transfer", [][3]string{
+		{"PENDING", "SENT", "SEND"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fromHook, fromCallback interface{}
+	err = f.OnEnterContext("SENT", func(ctx context.Context, pre string, cur string, action string) {
+		fromHook = ctx.Value(ctxKey("request"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey("request"), "REQ-1")
+	err = f.ExecContext(ctx, "SEND", "SENT", func(ctx context.Context, pre string, cur string, action string) {
+		fromCallback = ctx.Value(ctxKey("request"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromHook != "REQ-1" || fromCallback != "REQ-1" {
+		t.Errorf("hooks should receive the context, got %v and %v", fromHook, fromCallback)
+	}
+}
+
+func TestExecContextCanceled(t *testing.T) {
+	f, err := New("Transfer", [][3]string{
+		{"PENDING", "SENT", "SEND"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	f.OnBeforeAll(func(pre string, cur string, action string) {
+		called = true
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = f.ExecContext(ctx, "SEND", "SENT", nil)
+	if err != context.Canceled {
+		t.Errorf("should errored context canceled, got %v", err)
+	}
+	err = f.FireContext(ctx, "SEND", nil)
+	if err != context.Canceled {
+		t.Errorf("should errored context canceled, got %v", err)
+	}
+	if f.GetState() != "PENDING" {
+		t.Errorf("state should not change")
+	}
+	if called {
+		t.Errorf("hooks should not be called")
+	}
+
+	err = f.InitContext(ctx, "SENT")
+	if err != context.Canceled {
+		t.Errorf("should errored context canceled, got %v", err)
+	}
+	if f.GetState() != "PENDING" {
+		t.Errorf("state should not change")
+	}
+}
+
+func TestExecContextCanceledByGuard(t *testing.T) {
+	f, err := New("Transfer", [][3]string{
+		{"PENDING", "SENT", "SEND"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = f.AddGuard("PENDING", "SENT", "SEND", "SLOW_CHECK", func(pre string, cur string, action string) bool {
+		cancel()
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.ExecContext(ctx, "SEND", "SENT", nil)
+	if err != context.Canceled {
+		t.Errorf("should errored context canceled, got %v", err)
+	}
+	if f.GetState() != "PENDING" {
+		t.Errorf("state should not change")
+	}
+}
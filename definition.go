@@ -0,0 +1,272 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Definition is an immutable fsm definition shared by many instances
+// It is built once from a fsm, later changes of that fsm do not change the definition
+type Definition struct {
+	fsm *FSM
+}
+
+// Instance is a lightweight fsm running a shared definition
+// It shares the states, transitions, guards, hooks and timeouts of the definition,
+// it only owns its current state, the time its states were entered and its history
+type Instance struct {
+	def *Definition
+	// fsm is a view of the definition fsm with its own runtime fields,
+	// the definition fields are shared and never changed through it
+	fsm *FSM
+}
+
+// NewDefinition builds a definition from the states, transitions, final states, sub states, guards, hooks,
+// timeouts and metadata of the fsm, its initial state is the initial state of the instances
+func NewDefinition(f *FSM) *Definition {
+	return &Definition{fsm: f.clone()}
+}
+
+// clone copies the definition of the fsm, the current state, entered times and history are not copied
+func (f *FSM) clone() *FSM {
+	c := &FSM{
+		Name:           f.Name,
+		states:         make(map[string]bool, len(f.states)),
+		adj:            append([]transition{}, f.adj...),
+		initial:        f.initial,
+		allowFinalExit: f.allowFinalExit,
+		deterministic:  f.deterministic,
+		version:        f.version,
+		state:          createIntState(),
+		timeouts:       append([]timeout{}, f.timeouts...),
+		clock:          f.clock,
+		meta:           copyMeta(f.meta),
+	}
+	c.state.current = f.state.current
+	for s := range f.states {
+		c.states[s] = true
+	}
+	for s := range f.final {
+		if c.final == nil {
+			c.final = make(map[string]bool, len(f.final))
+		}
+		c.final[s] = true
+	}
+	if f.parents != nil {
+		c.parents = copyMeta(f.parents)
+	}
+	for v, m := range f.migrations {
+		if c.migrations == nil {
+			c.migrations = make(map[int]Migration, len(f.migrations))
+		}
+		c.migrations[v] = m
+	}
+	if f.guards != nil {
+		c.guards = make(map[transition][]guard, len(f.guards))
+		for t, gs := range f.guards {
+			c.guards[t] = append([]guard{}, gs...)
+		}
+	}
+	for s, meta := range f.stateMeta {
+		if c.stateMeta == nil {
+			c.stateMeta = make(map[string]map[string]string)
+		}
+		c.stateMeta[s] = copyMeta(meta)
+	}
+	c.hooks = hooks{
+		enter:     copyHooks(f.hooks.enter),
+		exit:      copyHooks(f.hooks.exit),
+		before:    copyHooks(f.hooks.before),
+		after:     copyHooks(f.hooks.after),
+		beforeAll: append([]ContextHook(nil), f.hooks.beforeAll...),
+		afterAll:  append([]ContextHook(nil), f.hooks.afterAll...),
+	}
+	return c
+}
+
+func copyHooks(hs map[string][]ContextHook) map[string][]ContextHook {
+	if hs == nil {
+		return nil
+	}
+	c := make(map[string][]ContextHook, len(hs))
+	for k, v := range hs {
+		c[k] = append([]ContextHook(nil), v...)
+	}
+	return c
+}
+
+// Name gets the name of the definition
+func (d *Definition) Name() string {
+	return d.fsm.Name
+}
+
+// Version gets the version of the definition
+func (d *Definition) Version() int {
+	return d.fsm.version
+}
+
+// GetInitial gets the initial state of the instances
+func (d *Definition) GetInitial() string {
+	return d.fsm.initial
+}
+
+// IsFinal reports whether the given state is a final state
+func (d *Definition) IsFinal(state string) bool {
+	return d.fsm.IsFinal(state)
+}
+
+// GetTrans returns the transitions string representation of the definition
+func (d *Definition) GetTrans() string {
+	return d.fsm.GetTrans()
+}
+
+// Analyze reports the problems of the definition
+func (d *Definition) Analyze() []Finding {
+	return d.fsm.Analyze()
+}
+
+// NewInstance creates an instance at the initial state
+func (d *Definition) NewInstance() *Instance {
+	i := &Instance{def: d, fsm: d.view()}
+	if d.fsm.initial != "" {
+		i.fsm.reset(d.fsm.initial)
+	}
+	return i
+}
+
+// view returns a fsm sharing the definition without current state, entered times and history
+func (d *Definition) view() *FSM {
+	v := *d.fsm
+	v.current = ""
+	v.entered = nil
+	v.recordHistory = false
+	v.history = nil
+	return &v
+}
+
+// Definition gets the definition the instance runs
+func (i *Instance) Definition() *Definition {
+	return i.def
+}
+
+// Init set the current state of the instance to the given state without running any hook
+// It validates state exists prior to set it to current
+func (i *Instance) Init(state string) error {
+	if ok := i.fsm.states[state]; !ok {
+		return ErrStateNotFound
+	}
+	i.fsm.reset(state)
+	return nil
+}
+
+// GetState gets the current state
+func (i *Instance) GetState() string {
+	return i.fsm.GetState()
+}
+
+// GetPath gets the current state preceded by its ancestors, outermost first
+func (i *Instance) GetPath() []string {
+	return i.fsm.GetPath()
+}
+
+// IsIn reports whether the given state is the current state or one of its ancestors
+func (i *Instance) IsIn(state string) bool {
+	return i.fsm.IsIn(state)
+}
+
+// Terminated reports whether the current state is a final state
+func (i *Instance) Terminated() bool {
+	return i.fsm.Terminated()
+}
+
+// Exec moves the instance to the given state using the given action, like FSM.Exec
+func (i *Instance) Exec(action string, des string, callback func(previous string, new string, action string)) error {
+	return i.fsm.Exec(action, des, callback)
+}
+
+// ExecContext is like Exec but the hooks and the callback receive the context
+func (i *Instance) ExecContext(ctx context.Context, action string, des string, callback ContextHook) error {
+	return i.fsm.ExecContext(ctx, action, des, callback)
+}
+
+// Fire executes the given action from the current state, like FSM.Fire
+func (i *Instance) Fire(action string, callback func(previous string, new string, action string)) error {
+	return i.fsm.Fire(action, callback)
+}
+
+// FireContext is like Fire but the hooks and the callback receive the context
+func (i *Instance) FireContext(ctx context.Context, action string, callback ContextHook) error {
+	return i.fsm.FireContext(ctx, action, callback)
+}
+
+// EnableHistory starts recording the transitions executed by the instance
+func (i *Instance) EnableHistory() {
+	i.fsm.EnableHistory()
+}
+
+// History returns a copy of the recorded transitions
+func (i *Instance) History() []Record {
+	return i.fsm.History()
+}
+
+// Pending returns the timers of the current state and its ancestors, sorted by deadline
+func (i *Instance) Pending() []Timer {
+	return i.fsm.Pending()
+}
+
+// Tick fires every timer due by now
+func (i *Instance) Tick(ctx context.Context) (int, error) {
+	return i.fsm.Tick(ctx)
+}
+
+// MarshalJSON writes the runtime data of the instance, the definition is not included
+func (i *Instance) MarshalJSON() ([]byte, error) {
+	var history *[]Record
+	if i.fsm.recordHistory {
+		h := append([]Record{}, i.fsm.history...)
+		history = &h
+	}
+	var entered map[string]time.Time
+	if len(i.fsm.timeouts) > 0 {
+		entered = i.fsm.entered
+	}
+	return json.Marshal(&struct {
+		Version int                  `json:"version,omitempty"`
+		Current string               `json:"current"`
+		Entered map[string]time.Time `json:"entered,omitempty"`
+		History *[]Record            `json:"history,omitempty"`
+	}{
+		Version: i.fsm.version,
+		Current: i.fsm.current,
+		Entered: entered,
+		History: history,
+	})
+}
+
+// UnmarshalJSON restores the runtime data of an instance created by a definition
+// The data of an older version is upgraded by the migrations of the definition
+func (i *Instance) UnmarshalJSON(data []byte) error {
+	temp := struct {
+		Version int                  `json:"version"`
+		Current string               `json:"current"`
+		Entered map[string]time.Time `json:"entered"`
+		History []Record             `json:"history"`
+	}{}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+	if i.def == nil {
+		return ErrNotReady
+	}
+	// an instance without current state is not initialized
+	if temp.Current == "" {
+		i.fsm.current = ""
+		i.fsm.entered = nil
+	} else if err := i.fsm.upgrade(temp.Version, temp.Current, temp.Entered); err != nil {
+		return err
+	}
+	i.fsm.recordHistory = temp.History != nil
+	i.fsm.history = temp.History
+	return nil
+}
@@ -0,0 +1,131 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefinition(t *testing.T) {
+	p, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entered := 0
+	if err = p.State.OnEnter("BASIC", func(previous, new, action string) {
+		entered++
+	}); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDefinition(p.State)
+
+	// the fsm the definition was built from can change without changing the definition
+	if err = p.State.AddState("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.State.AddTrans("TRIAL", "GOLD", "UPGRATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	a := d.NewInstance()
+	b := d.NewInstance()
+	if a.GetState() != "TRIAL" || b.GetState() != "TRIAL" {
+		t.Fatalf("states should be TRIAL, got %v and %v", a.GetState(), b.GetState())
+	}
+	if err = a.Exec("UPGRATE", "BASIC", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Exec("UPGRATE", "GOLD", nil); err != ErrStateNotFound {
+		t.Errorf("should errored state not found, got %v", err)
+	}
+	if a.GetState() != "BASIC" || b.GetState() != "TRIAL" {
+		t.Errorf("states should be BASIC and TRIAL, got %v and %v", a.GetState(), b.GetState())
+	}
+	if entered != 1 {
+		t.Errorf("hook should be called once, got %v", entered)
+	}
+	if a.Definition() != d || d.GetInitial() != "TRIAL" {
+		t.Errorf("wrong definition: %v", d.GetInitial())
+	}
+}
+
+func TestInstanceFinal(t *testing.T) {
+	p, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.State.AddFinal("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	i := NewDefinition(p.State).NewInstance()
+	if err = i.Exec("UPGRATE", "GOLD", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !i.Terminated() {
+		t.Errorf("instance should be terminated at GOLD")
+	}
+	if err = i.Fire("DOWNGRATE", nil); !errors.Is(err, ErrFinalState) {
+		t.Errorf("should errored final state, got %v", err)
+	}
+}
+
+func TestInstanceMarshal(t *testing.T) {
+	p, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDefinition(p.State)
+	i := d.NewInstance()
+	i.EnableHistory()
+	if err = i.Exec("UPGRATE", "BASIC", nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the definition is not part of the instance
+	if strings.Contains(string(data), "transitions") {
+		t.Errorf("instance JSON should not contain the definition: %s", data)
+	}
+
+	j := d.NewInstance()
+	if err = json.Unmarshal(data, j); err != nil {
+		t.Fatal(err)
+	}
+	if j.GetState() != "BASIC" {
+		t.Errorf("state should be BASIC, got %v", j.GetState())
+	}
+	if h := j.History(); len(h) != 1 || h[0].Action != "UPGRATE" {
+		t.Errorf("wrong history: %v", h)
+	}
+	if err = json.Unmarshal([]byte(`{"current":"LOST"}`), j); err != ErrStateNotFound {
+		t.Errorf("should errored state not found, got %v", err)
+	}
+	if err = json.Unmarshal(data, &Instance{}); err != ErrNotReady {
+		t.Errorf("should errored not ready, got %v", err)
+	}
+}
+
+func TestInstanceMarshalNoInitial(t *testing.T) {
+	f, err := New("Plan", [][3]string{{"TRIAL", "BASIC", "UPGRATE"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDefinition(f)
+	data, err := json.Marshal(d.NewInstance())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := d.NewInstance()
+	if err = i.Init("BASIC"); err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, i); err != nil {
+		t.Fatal(err)
+	}
+	if i.GetState() != "" {
+		t.Errorf("instance should not be initialized, got %v", i.GetState())
+	}
+}
@@ -0,0 +1,157 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// setPrefix starts the names of the set states created by Determinize
+const setPrefix = "SET"
+
+// SetDeterministic enables or disables the deterministic mode
+// In deterministic mode AddTrans rejects a transition whose state and action already lead to another state
+// It fails with ErrNondeterministic when enabled on a fsm that is already nondeterministic
+func (f *FSM) SetDeterministic(deterministic bool) error {
+	if deterministic {
+		if err := f.checkDeterministic(); err != nil {
+			return err
+		}
+	}
+	f.deterministic = deterministic
+	return nil
+}
+
+// IsDeterministic reports whether every state and action lead to one state at most
+func (f *FSM) IsDeterministic() bool {
+	return f.checkDeterministic() == nil
+}
+
+// checkDeterministic returns the first pair of transitions sharing their state and action
+func (f *FSM) checkDeterministic() error {
+	for i, a := range f.adj {
+		for _, b := range f.adj[:i] {
+			if a.From == b.From && a.Action == b.Action && a.To != b.To {
+				return nondeterministic(a.From, a.Action, b.To, a.To)
+			}
+		}
+	}
+	return nil
+}
+
+func nondeterministic(src string, action string, targets ...string) error {
+	return fmt.Errorf("%w: %v from %v leads to %v", ErrNondeterministic, action, src, strings.Join(targets, ", "))
+}
+
+// namer returns a function generating the state names prefix_A to prefix_Z, then prefix_AA, prefix_AB...
+// The names that are states of f are skipped
+func namer(f *FSM, prefix string) func() string {
+	i := 0
+	return func() string {
+		for {
+			i++
+			var letters []byte
+			for n := i; n > 0; n = (n - 1) / 26 {
+				letters = append([]byte{byte('A' + (n-1)%26)}, letters...)
+			}
+			name := prefix + "_" + string(letters)
+			if !f.states[name] {
+				return name
+			}
+		}
+	}
+}
+
+// Determinize converts the fsm into an equivalent deterministic fsm using the subset construction
+// Every set of states reachable with the same action becomes a state named SET_A, SET_B... in the order they are found,
+// a set state is final when any of its members is final
+// It also returns the sorted members of every set state
+// Every state of the fsm is kept, sub states are flattened: the inherited transitions become transitions of the sub state
+// The initial and current states and the metadata are kept, the guards, hooks and timeouts are not
+func Determinize(f *FSM) (*FSM, map[string][]string, error) {
+	actions := f.Actions()
+
+	d := NewFSM(f.Name)
+	d.meta = copyMeta(f.meta)
+	members := make(map[string][]string)
+	names := make(map[string]string)
+	next := namer(f, setPrefix)
+	var queue []string
+	add := func(set []string) (string, error) {
+		key := strings.Join(set, " ")
+		if name, ok := names[key]; ok {
+			return name, nil
+		}
+		name := set[0]
+		if len(set) > 1 {
+			name = next()
+		}
+		if err := d.AddState(name); err != nil {
+			return "", err
+		}
+		names[key] = name
+		members[name] = set
+		queue = append(queue, name)
+		for _, s := range set {
+			if f.final[s] {
+				return name, d.AddFinal(name)
+			}
+		}
+		return name, nil
+	}
+	for _, s := range f.stateNames() {
+		if _, err := add([]string{s}); err != nil {
+			return nil, nil, err
+		}
+	}
+	for len(queue) > 0 {
+		src := queue[0]
+		queue = queue[1:]
+		for _, action := range actions {
+			set := make(map[string]bool)
+			for _, s := range members[src] {
+				for _, des := range f.targets(s, action) {
+					set[des] = true
+				}
+			}
+			if len(set) == 0 {
+				continue
+			}
+			sorted := make([]string, 0, len(set))
+			for s := range set {
+				sorted = append(sorted, s)
+			}
+			sort.Strings(sorted)
+			des, err := add(sorted)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err = d.AddTrans(src, des, action); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for s, meta := range f.stateMeta {
+		for k, v := range meta {
+			if err := d.SetStateMeta(s, k, v); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	d.deterministic = true
+	if f.initial != "" {
+		if err := d.Init(f.initial); err != nil {
+			return nil, nil, err
+		}
+	}
+	if f.current != "" {
+		d.reset(f.current)
+	}
+	sets := make(map[string][]string)
+	for name, set := range members {
+		if len(set) > 1 {
+			sets[name] = set
+		}
+	}
+	return d, sets, nil
+}
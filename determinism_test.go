@@ -0,0 +1,171 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeterministicMode(t *testing.T) {
+	// UPGRATE leads from TRIAL to BASIC and to PREMIUM
+	p, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State.IsDeterministic() {
+		t.Errorf("plan should be nondeterministic")
+	}
+	if err = p.State.SetDeterministic(true); !errors.Is(err, ErrNondeterministic) {
+		t.Errorf("should errored nondeterministic transition, got %v", err)
+	}
+
+	f, err := New("Plan", [][3]string{
+		{"TRIAL", "BASIC", "UPGRATE"},
+		{"BASIC", "PREMIUM", "UPGRATE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetDeterministic(true); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddTrans("TRIAL", "PREMIUM", "UPGRATE"); !errors.Is(err, ErrNondeterministic) {
+		t.Errorf("should errored nondeterministic transition, got %v", err)
+	}
+	if err = f.AddTrans("TRIAL", "BASIC", "UPGRATE"); err != ErrTransAlExists {
+		t.Errorf("should errored transition already exists, got %v", err)
+	}
+	if err = f.AddTrans("PREMIUM", "BASIC", "DOWNGRADE"); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err = f.Init("TRIAL"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewFSM("")
+	if err = json.Unmarshal(data, g); err != nil {
+		t.Fatal(err)
+	}
+	if err = g.AddTrans("TRIAL", "PREMIUM", "UPGRATE"); !errors.Is(err, ErrNondeterministic) {
+		t.Errorf("deterministic mode should survive a round trip, got %v", err)
+	}
+}
+
+func TestDeterminize(t *testing.T) {
+	f, err := New("Plan", [][3]string{
+		{"TRIAL", "BASIC", "UPGRATE"},
+		{"TRIAL", "PREMIUM", "UPGRATE"},
+		{"BASIC", "CANCELED", "CANCEL"},
+		{"PREMIUM", "GOLD", "UPGRATE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddFinal("CANCELED"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Init("TRIAL"); err != nil {
+		t.Fatal(err)
+	}
+
+	d, sets, err := Determinize(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.IsDeterministic() || !d.deterministic {
+		t.Errorf("fsm should be deterministic")
+	}
+	if !reflect.DeepEqual(sets, map[string][]string{"SET_A": {"BASIC", "PREMIUM"}}) {
+		t.Errorf("wrong set states: %v", sets)
+	}
+	want := []string{"BASIC", "CANCELED", "GOLD", "PREMIUM", "SET_A", "TRIAL"}
+	if got := d.stateNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong states: %v", got)
+	}
+	if d.GetInitial() != "TRIAL" || d.GetState() != "TRIAL" {
+		t.Errorf("state should be TRIAL, got %v", d.GetState())
+	}
+	for _, step := range [][2]string{
+		{"UPGRATE", "SET_A"},
+		{"UPGRATE", "GOLD"},
+	} {
+		if err = d.Fire(step[0], nil); err != nil {
+			t.Fatal(err)
+		}
+		if d.GetState() != step[1] {
+			t.Errorf("state should be %v, got %v", step[1], d.GetState())
+		}
+	}
+	if _, ok := d.findTrans("SET_A", "CANCELED", "CANCEL"); !ok {
+		t.Errorf("SET_A should be canceled")
+	}
+	if d.IsFinal("SET_A") || !d.IsFinal("CANCELED") {
+		t.Errorf("wrong final states: %v", d.finalNames())
+	}
+}
+
+func TestDeterminizeSubStates(t *testing.T) {
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tenant.State
+	if err := f.AddTrans("TRIAL", "PREMIUM", "UPGRADE"); err != nil {
+		t.Fatal(err)
+	}
+	d, sets, err := Determinize(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sets, map[string][]string{"SET_A": {"BASIC", "PREMIUM"}, "SET_B": {"GOLD", "PREMIUM"}}) {
+		t.Errorf("wrong set states: %v", sets)
+	}
+	// the inherited SUSPEND becomes a transition of every sub state
+	if _, ok := d.findTrans("SET_A", "SUSPENDED", "SUSPEND"); !ok {
+		t.Errorf("SET_A should be suspended")
+	}
+	if _, ok := d.findTrans("SET_A", "SET_B", "UPGRADE"); !ok {
+		t.Errorf("SET_A should be upgraded\n%v", d.GetTrans())
+	}
+	if len(d.parents) != 0 {
+		t.Errorf("sub states should be flattened, got %v", d.parents)
+	}
+}
+
+func TestDeterminizeNames(t *testing.T) {
+	long := strings.Repeat("A", 40)
+	f, err := New("Plan", [][3]string{
+		{"TRIAL", long, "UPGRATE"},
+		{"TRIAL", "B" + long[1:], "UPGRATE"},
+		{long, "SET_A", "DOWNGRADE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, sets, err := Determinize(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SET_A is a state of the fsm, the set state gets the next name
+	if !reflect.DeepEqual(sets, map[string][]string{"SET_B": {long, "B" + long[1:]}}) {
+		t.Errorf("wrong set states: %v", sets)
+	}
+	if _, ok := d.findTrans("TRIAL", "SET_B", "UPGRATE"); !ok {
+		t.Errorf("TRIAL should be upgraded to SET_B\n%v", d.GetTrans())
+	}
+
+	next := namer(NewFSM("Names"), setPrefix)
+	var names []string
+	for i := 0; i < 28; i++ {
+		names = append(names, next())
+	}
+	if names[0] != "SET_A" || names[25] != "SET_Z" || names[26] != "SET_AA" || names[27] != "SET_AB" {
+		t.Errorf("wrong generated names: %v", names)
+	}
+}
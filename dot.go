@@ -0,0 +1,94 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DOTOptions controls how DOT renders a fsm, the zero value renders with defaults
+type DOTOptions struct {
+	// Direction is the Graphviz rankdir: LR (default), RL, TB or BT
+	Direction string
+	// Shape is the shape of the states, circle by default
+	Shape string
+	// CurrentColor is the fill color of the current state, lightblue by default
+	CurrentColor string
+	// FontName is the font of states and transitions, Graphviz default when empty
+	FontName string
+	// NoCurrent disables the highlight of the current state
+	NoCurrent bool
+}
+
+// DOT returns the Graphviz DOT representation of the fsm
+// Transitions are labelled by their action, the initial state has an arrow from a point,
+// final states are drawn with a double border and the current state is filled
+// Sub states are grouped in a cluster with their parent
+func (f *FSM) DOT(opts DOTOptions) string {
+	if opts.Direction == "" {
+		opts.Direction = "LR"
+	}
+	if opts.Shape == "" {
+		opts.Shape = "circle"
+	}
+	if opts.CurrentColor == "" {
+		opts.CurrentColor = "lightblue"
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("digraph %v {\n", dotQuote(f.Name)))
+	builder.WriteString(fmt.Sprintf("\trankdir=%v;\n", opts.Direction))
+	if opts.FontName != "" {
+		builder.WriteString(fmt.Sprintf("\tnode [shape=%v, fontname=%v];\n", opts.Shape, dotQuote(opts.FontName)))
+		builder.WriteString(fmt.Sprintf("\tedge [fontname=%v];\n", dotQuote(opts.FontName)))
+	} else {
+		builder.WriteString(fmt.Sprintf("\tnode [shape=%v];\n", opts.Shape))
+	}
+
+	if f.initial != "" {
+		builder.WriteString("\t\"__start\" [shape=point];\n")
+		builder.WriteString(fmt.Sprintf("\t\"__start\" -> %v;\n", dotQuote(f.initial)))
+	}
+
+	f.writeDOTStates(&builder, opts, f.children(), "", "\t")
+
+	for _, adj := range f.adj {
+		builder.WriteString(fmt.Sprintf("\t%v -> %v [label=%v];\n", dotQuote(adj.From), dotQuote(adj.To), dotQuote(adj.Action)))
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// writeDOTStates writes the children of the given parent, the ones with children become clusters
+func (f *FSM) writeDOTStates(builder *strings.Builder, opts DOTOptions, children map[string][]string, parent string, indent string) {
+	for _, name := range children[parent] {
+		if len(children[name]) == 0 {
+			builder.WriteString(indent + f.dotState(opts, name))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%vsubgraph %v {\n", indent, dotQuote("cluster_"+name)))
+		builder.WriteString(fmt.Sprintf("%v\tlabel=%v;\n", indent, dotQuote(name)))
+		builder.WriteString(indent + "\t" + f.dotState(opts, name))
+		f.writeDOTStates(builder, opts, children, name, indent+"\t")
+		builder.WriteString(indent + "}\n")
+	}
+}
+
+func (f *FSM) dotState(opts DOTOptions, name string) string {
+	var attrs []string
+	if f.final[name] {
+		attrs = append(attrs, "shape=doublecircle")
+	}
+	if !opts.NoCurrent && name == f.current {
+		attrs = append(attrs, "style=filled", "fillcolor="+dotQuote(opts.CurrentColor))
+	}
+	if len(attrs) == 0 {
+		return fmt.Sprintf("%v;\n", dotQuote(name))
+	}
+	return fmt.Sprintf("%v [%v];\n", dotQuote(name), strings.Join(attrs, ", "))
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
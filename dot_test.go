@@ -0,0 +1,81 @@
+package fsm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDOT(t *testing.T) {
+	a, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := a.State
+	if err = f.AddState("CANCELED"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddTrans("TRIAL", "CANCELED", "CANCEL"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddFinal("CANCELED"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Exec("UPGRATE", "BASIC", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `digraph "SAAS Account State V1.0" {
+	rankdir=LR;
+	node [shape=circle];
+	"__start" [shape=point];
+	"__start" -> "TRIAL";
+	"BASIC" [style=filled, fillcolor="lightblue"];
+	"CANCELED" [shape=doublecircle];
+	"PREMIUM";
+	"TRIAL";
+	"TRIAL" -> "BASIC" [label="UPGRATE"];
+	"TRIAL" -> "PREMIUM" [label="UPGRATE"];
+	"BASIC" -> "PREMIUM" [label="UPGRATE"];
+	"PREMIUM" -> "BASIC" [label="DOWNGRATE"];
+	"TRIAL" -> "CANCELED" [label="CANCEL"];
+}
+`
+	if got := f.DOT(DOTOptions{}); got != expected {
+		t.Errorf("wrong DOT:\n%v", got)
+	}
+
+	got := f.DOT(DOTOptions{Direction: "TB", Shape: "box", FontName: "Helvetica", NoCurrent: true})
+	for _, want := range []string{"rankdir=TB;", `node [shape=box, fontname="Helvetica"];`, `edge [fontname="Helvetica"];`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DOT should contain %v:\n%v", want, got)
+		}
+	}
+	if strings.Contains(got, "fillcolor") {
+		t.Errorf("current state should not be highlighted:\n%v", got)
+	}
+}
+
+func TestDOTSubStates(t *testing.T) {
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tenant.State
+	got := f.DOT(DOTOptions{NoCurrent: true})
+	expected := `	subgraph "cluster_ACTIVE" {
+		label="ACTIVE";
+		"ACTIVE";
+		"BASIC";
+		subgraph "cluster_PREMIUM" {
+			label="PREMIUM";
+			"PREMIUM";
+			"GOLD";
+		}
+		"TRIAL";
+	}
+	"SUSPENDED";
+`
+	if !strings.Contains(got, expected) {
+		t.Errorf("sub states should be clustered:\n%v", got)
+	}
+}
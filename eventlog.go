@@ -0,0 +1,186 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Event is an executed action stored in an EventLog
+type Event struct {
+	Seq    int64     `json:"seq"`
+	Action string    `json:"action"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+}
+
+// EventLog is an append-only log of the actions executed by the fsm of each entity.
+// The events of an entity are numbered from 1 in the order they were appended.
+type EventLog interface {
+	// Append adds the events after the last one of the entity and returns the seq of the last one
+	// last is the seq of the last event known by the caller, 0 when the entity has no events,
+	// if the log has more events it fails with ErrVersionConflict
+	Append(ctx context.Context, id string, last int64, events ...Event) (int64, error)
+	// Events returns the events of the entity with a seq greater than after
+	Events(ctx context.Context, id string, after int64) ([]Event, error)
+}
+
+// Snapshot is the state of an entity after the event with the given seq
+type Snapshot struct {
+	Seq     int64  `json:"seq"`
+	Current string `json:"current"`
+}
+
+// TakeSnapshot returns the snapshot of the fsm after the event with the given seq
+func (f *FSM) TakeSnapshot(seq int64) Snapshot {
+	return Snapshot{Seq: seq, Current: f.current}
+}
+
+// Replay moves the fsm from its current state through the events, in order
+// Every event is validated against the transitions of the fsm
+// Guards, hooks and history are not involved, the events were already executed
+// The states of every event are entered at its time, so the timers are due as they were
+func Replay(f *FSM, events []Event) error {
+	return replay(f, events, time.Time{})
+}
+
+// ReplayUntil is like Replay but it stops at the first event after the given time,
+// the fsm ends in the state the entity was at that time
+func ReplayUntil(f *FSM, events []Event, at time.Time) error {
+	return replay(f, events, at)
+}
+
+// ReplaySnapshot sets the fsm to the snapshot and replays the events after it
+// The first event must follow the snapshot
+func ReplaySnapshot(f *FSM, snap Snapshot, events []Event) error {
+	if len(events) > 0 && events[0].Seq != snap.Seq+1 {
+		return fmt.Errorf("%w: seq %v does not follow snapshot %v", ErrInvalidEvent, events[0].Seq, snap.Seq)
+	}
+	if ok := f.states[snap.Current]; !ok {
+		return ErrStateNotFound
+	}
+	f.reset(snap.Current)
+	return Replay(f, events)
+}
+
+func replay(f *FSM, events []Event, until time.Time) error {
+	for i, e := range events {
+		if i > 0 && e.Seq != events[i-1].Seq+1 {
+			return fmt.Errorf("%w: seq %v follows %v", ErrInvalidEvent, e.Seq, events[i-1].Seq)
+		}
+		if !until.IsZero() && e.At.After(until) {
+			return nil
+		}
+		if e.From != f.current {
+			return fmt.Errorf("%w: seq %v starts at %v but fsm is at %v", ErrInvalidEvent, e.Seq, e.From, f.current)
+		}
+		if _, ok := f.lookupTrans(e.From, e.To, e.Action); !ok {
+			return fmt.Errorf("%w: seq %v: %v", ErrExecNotAllowed, e.Seq, e.Action)
+		}
+		f.step(e.At, e.To)
+	}
+	return nil
+}
+
+// Rebuild sets the fsm to the state of the entity by replaying its events
+// The events are replayed from snap, or from the current state of f when snap is nil
+// It returns the seq of the last event
+func Rebuild(ctx context.Context, log EventLog, id string, f *FSM, snap *Snapshot) (int64, error) {
+	var after int64
+	if snap != nil {
+		after = snap.Seq
+	}
+	events, err := log.Events(ctx, id, after)
+	if err != nil {
+		return 0, err
+	}
+	if snap != nil {
+		err = ReplaySnapshot(f, *snap, events)
+	} else {
+		err = Replay(f, events)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(events) > 0 {
+		after = events[len(events)-1].Seq
+	}
+	return after, nil
+}
+
+// ExecLogged executes the action and appends it to the events of the entity
+// last is the seq of the last event replayed into f
+// It returns the seq of the new event, the event is appended before the hooks and the callback are called
+// and f does not move when the append fails
+func ExecLogged(ctx context.Context, log EventLog, id string, f *FSM, last int64, action string, des string, callback ContextHook) (int64, error) {
+	if err := f.check(ctx, action, des); err != nil {
+		return 0, err
+	}
+	at := f.now()
+	seq, err := log.Append(ctx, id, last, Event{
+		Action: action,
+		From:   f.current,
+		To:     des,
+		At:     at,
+	})
+	if err != nil {
+		return 0, err
+	}
+	f.move(ctx, at, action, des, callback)
+	return seq, nil
+}
+
+// MemEventLog is an EventLog that keeps the events in memory
+type MemEventLog struct {
+	mu     sync.Mutex
+	events map[string][]Event
+}
+
+// NewMemEventLog creates a pointer to a brand new MemEventLog
+func NewMemEventLog() *MemEventLog {
+	return &MemEventLog{
+		events: make(map[string][]Event),
+	}
+}
+
+func (m *MemEventLog) Append(ctx context.Context, id string, last int64, events ...Event) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.events[id]
+	if int64(len(stored)) != last {
+		return 0, ErrVersionConflict
+	}
+	for _, e := range events {
+		last++
+		e.Seq = last
+		stored = append(stored, e)
+	}
+	m.events[id] = stored
+	return last, nil
+}
+
+func (m *MemEventLog) Events(ctx context.Context, id string, after int64) ([]Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.events[id]
+	if after < 0 {
+		after = 0
+	}
+	if after >= int64(len(stored)) {
+		return nil, nil
+	}
+	events := make([]Event, len(stored)-int(after))
+	copy(events, stored[after:])
+	return events, nil
+}
@@ -0,0 +1,137 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventLogReplay(t *testing.T) {
+	ctx := context.Background()
+	log := NewMemEventLog()
+
+	a, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last int64
+	for _, exec := range [][2]string{
+		{"UPGRATE", "PREMIUM"},
+		{"DOWNGRATE", "BASIC"},
+		{"UPGRATE", "PREMIUM"},
+	} {
+		last, err = ExecLogged(ctx, log, "ACC1", a.State, last, exec[0], exec[1], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if last != 3 {
+		t.Errorf("last seq should be 3, got %v", last)
+	}
+
+	stale, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	_, err = ExecLogged(ctx, log, "ACC1", stale.State, 0, "UPGRATE", "PREMIUM", func(ctx context.Context, pre string, cur string, action string) {
+		called = true
+	})
+	if err != ErrVersionConflict {
+		t.Errorf("should errored version conflict, got %v", err)
+	}
+	if called || stale.State.GetState() != "BASIC" {
+		t.Errorf("fsm should not move when the append fails, state %v", stale.State.GetState())
+	}
+
+	rebuilt, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, err := Rebuild(ctx, log, "ACC1", rebuilt.State, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 3 || rebuilt.State.GetState() != "PREMIUM" {
+		t.Errorf("wrong rebuild: seq %v state %v", seq, rebuilt.State.GetState())
+	}
+
+	snap := Snapshot{Seq: 2, Current: "BASIC"}
+	fromSnap, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, err = Rebuild(ctx, log, "ACC1", fromSnap.State, &snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 3 || fromSnap.State.GetState() != "PREMIUM" {
+		t.Errorf("wrong rebuild from snapshot: seq %v state %v", seq, fromSnap.State.GetState())
+	}
+	if s := fromSnap.State.TakeSnapshot(seq); s.Seq != 3 || s.Current != "PREMIUM" {
+		t.Errorf("wrong snapshot: %+v", s)
+	}
+}
+
+func TestReplayUntil(t *testing.T) {
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Seq: 1, Action: "UPGRATE", From: "BASIC", To: "PREMIUM", At: start},
+		{Seq: 2, Action: "DOWNGRATE", From: "PREMIUM", To: "BASIC", At: start.Add(time.Hour)},
+		{Seq: 3, Action: "UPGRATE", From: "BASIC", To: "PREMIUM", At: start.Add(2 * time.Hour)},
+	}
+
+	a, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ReplayUntil(a.State, events, start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.State.GetState() != "BASIC" {
+		t.Errorf("state should be BASIC, got %v", a.State.GetState())
+	}
+}
+
+func TestReplayInvalid(t *testing.T) {
+	for _, test := range []struct {
+		events []Event
+		err    error
+	}{
+		// DOWNGRATE does not lead from PREMIUM to PREMIUM
+		{[]Event{
+			{Seq: 1, Action: "UPGRATE", From: "BASIC", To: "PREMIUM"},
+			{Seq: 2, Action: "DOWNGRATE", From: "PREMIUM", To: "PREMIUM"},
+		}, ErrExecNotAllowed},
+		// the fsm is not at PREMIUM
+		{[]Event{
+			{Seq: 1, Action: "DOWNGRATE", From: "PREMIUM", To: "BASIC"},
+		}, ErrInvalidEvent},
+		// seq 2 is missing
+		{[]Event{
+			{Seq: 1, Action: "UPGRATE", From: "BASIC", To: "PREMIUM"},
+			{Seq: 3, Action: "DOWNGRATE", From: "PREMIUM", To: "BASIC"},
+		}, ErrInvalidEvent},
+	} {
+		a, err := NewPlan("BASIC")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = Replay(a.State, test.events); !errors.Is(err, test.err) {
+			t.Errorf("should errored %v, got %v", test.err, err)
+		}
+	}
+
+	a, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ReplaySnapshot(a.State, Snapshot{Seq: 5, Current: "BASIC"}, []Event{
+		{Seq: 7, Action: "UPGRATE", From: "BASIC", To: "PREMIUM"},
+	})
+	if !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("should errored invalid event after snapshot, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFinalState(t *testing.T) {
+	p, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := p.State
+	if err = f.AddFinal("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddFinal("MISSING"); err != ErrStateNotFound {
+		t.Errorf("should errored state not found, got %v", err)
+	}
+	if f.Terminated() {
+		t.Errorf("fsm should not be terminated at TRIAL")
+	}
+	if err = f.Exec("UPGRATE", "GOLD", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Terminated() {
+		t.Errorf("fsm should be terminated at GOLD")
+	}
+	if err = f.Exec("DOWNGRATE", "PREMIUM", nil); !errors.Is(err, ErrFinalState) {
+		t.Errorf("should errored final state, got %v", err)
+	}
+	if err = f.Fire("DOWNGRATE", nil); !errors.Is(err, ErrFinalState) {
+		t.Errorf("should errored final state, got %v", err)
+	}
+	if err = f.Fire("UPGRATE", nil); err != ErrExecNotAllowed {
+		t.Errorf("should errored exec not allowed, got %v", err)
+	}
+	if f.GetState() != "GOLD" {
+		t.Errorf("state should be GOLD, got %v", f.GetState())
+	}
+
+	f.SetAllowFinalExit(true)
+	if err = f.Exec("DOWNGRATE", "BASIC", nil); err != nil {
+		t.Fatal(err)
+	}
+	if f.Terminated() {
+		t.Errorf("fsm should not be terminated at BASIC")
+	}
+}
+
+func TestFinalRegion(t *testing.T) {
+	p, err := NewTenantRegions("TRIAL", "PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	billing := p.Region("BILLING")
+	if err := billing.AddFinal("CANCELED"); err != nil {
+		t.Fatal(err)
+	}
+	if err := billing.Fire("CANCEL", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the terminated region has no transition for PROVISION and is left out
+	if err := p.Fire("PROVISION", nil); err != nil {
+		t.Errorf("a terminated region should not block other regions, got %v", err)
+	}
+	state := p.GetState()
+	if state["BILLING"] != "CANCELED" || state["PROVISIONING"] != "READY" {
+		t.Errorf("only provisioning should move: %v", state)
+	}
+}
+
+func TestFinalRegionWithTrans(t *testing.T) {
+	p, err := NewTenantRegions("TRIAL", "PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	billing := p.Region("BILLING")
+	if err = billing.AddFinal("OVERDUE"); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"PAY", "EXPIRE"} {
+		if err = billing.Fire(action, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the terminated region has a transition for CANCEL but it does not leave its state
+	if err = p.Fire("CANCEL", nil); err != nil {
+		t.Errorf("a terminated region should not block other regions, got %v", err)
+	}
+	state := p.GetState()
+	if state["BILLING"] != "OVERDUE" || state["PROVISIONING"] != "DELETED" {
+		t.Errorf("only provisioning should move: %v", state)
+	}
+}
+
+func TestFinalStateTimeout(t *testing.T) {
+	p, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := p.State
+	clock := NewManualClock(f.now())
+	f.SetClock(clock)
+	if err = f.AddFinal("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddTimeout("GOLD", time.Minute, "DOWNGRATE"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Exec("UPGRATE", "GOLD", nil); err != nil {
+		t.Fatal(err)
+	}
+	if timers := f.Pending(); len(timers) != 0 {
+		t.Errorf("no timer should be pending at a final state, got %v", timers)
+	}
+	clock.Advance(time.Hour)
+	if n, err := f.Tick(context.Background()); n != 0 || err != nil {
+		t.Errorf("no timer should fire at a final state, got %v %v", n, err)
+	}
+}
+
+func TestMarshalInitialFinal(t *testing.T) {
+	p, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := p.State
+	if err = f.AddFinal("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	f.SetAllowFinalExit(true)
+	if err = f.Exec("UPGRATE", "BASIC", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f2 FSM
+	if err = json.Unmarshal(b, &f2); err != nil {
+		t.Fatal(err)
+	}
+	if f2.GetInitial() != "TRIAL" || f2.GetState() != "BASIC" {
+		t.Errorf("wrong initial or current state: %v %v", f2.GetInitial(), f2.GetState())
+	}
+	if !f2.IsFinal("GOLD") || f2.IsFinal("BASIC") {
+		t.Errorf("wrong final states: %v", f2.finalNames())
+	}
+	if !f2.allowFinalExit {
+		t.Errorf("leaving final states should be allowed")
+	}
+}
@@ -0,0 +1,83 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFire(t *testing.T) {
+	f, err := New("Payment", [][3]string{
+		{"PENDING", "PAID", "PAYMENT_RECEIVED"},
+		{"PENDING", "CANCELED", "CANCEL"},
+		{"PAID", "REFUNDED", "REFUND"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var previous, current string
+	err = f.Fire("PAYMENT_RECEIVED", func(pre string, cur string, action string) {
+		previous = pre
+		current = cur
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GetState() != "PAID" {
+		t.Errorf("state should be PAID, got %v", f.GetState())
+	}
+	if previous != "PENDING" || current != "PAID" {
+		t.Errorf("wrong callback arguments: %v -> %v", previous, current)
+	}
+
+	err = f.Fire("CANCEL", nil)
+	if err != ErrExecNotAllowed {
+		t.Errorf("should errored exec not allowed, got %v", err)
+	}
+}
+
+func TestFireAmbiguous(t *testing.T) {
+	f, err := New("Three States", [][3]string{
+		{"TRIAL", "BASIC", "UPGRATE"},
+		{"TRIAL", "PREMIUM", "UPGRATE"},
+		{"BASIC", "PREMIUM", "UPGRATE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Fire("UPGRATE", nil)
+	if !errors.Is(err, ErrAmbiguousTrans) {
+		t.Errorf("should errored ambiguous transition, got %v", err)
+	}
+	if f.GetState() != "TRIAL" {
+		t.Errorf("state should not change")
+	}
+
+	err = f.Init("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Fire("UPGRATE", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GetState() != "PREMIUM" {
+		t.Errorf("state should be PREMIUM")
+	}
+}
+
+func TestFireNotReady(t *testing.T) {
+	f := NewFSM("EMPTY")
+	if err := f.Fire("DO", nil); err != ErrNotReady {
+		t.Errorf("should errored not ready, got %v", err)
+	}
+}
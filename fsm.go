@@ -5,10 +5,13 @@
 package fsm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
 var ErrStateNotFound error = errors.New("state not found")
@@ -18,6 +21,19 @@ var ErrTransAlExists = errors.New("transition already exists")
 var ErrInvalidName = errors.New("invalid name")
 var ErrExecNotAllowed = errors.New("execution not allowed")
 var ErrNotReady = errors.New("not ready")
+var ErrTransNotFound = errors.New("transition not found")
+var ErrGuardAlExists = errors.New("guard already exists")
+var ErrGuardRejected = errors.New("guard rejected")
+var ErrInvalidGuard = errors.New("invalid guard")
+var ErrAmbiguousTrans = errors.New("ambiguous transition")
+var ErrInvalidParent = errors.New("invalid parent")
+var ErrTimeoutAlExists = errors.New("timeout already exists")
+var ErrInvalidTimeout = errors.New("invalid timeout")
+var ErrFinalState = errors.New("final state")
+var ErrNondeterministic = errors.New("nondeterministic transition")
+var ErrInvalidVersion = errors.New("invalid version")
+var ErrInvalidMigration = errors.New("invalid migration")
+var ErrInvalidHook = errors.New("invalid hook")
 
 var exp = regexp.MustCompile(`^[A-Z]+(_?[A-Z])*$`)
 
@@ -43,10 +59,38 @@ type FSM struct {
 	states  map[string]bool
 	adj     []transition
 	current string
+	// initial is the state given to Init
+	initial string
+	// final are the accepting states
+	final map[string]bool
+	// allowFinalExit allows to leave the final states
+	allowFinalExit bool
+	// deterministic rejects the transitions leading to several states with the same action
+	deterministic bool
+	// version is the version of the definition, migrations upgrade older versions to it
+	version    int
+	migrations map[int]Migration
 	// state is the internal fsm state
 	state *FSM
 	// isInt stands for isInternal, flag to determine if this state is an internal state, used in conjunction with state field
 	isInternal bool
+	// guards are the predicates evaluated before executing a transition
+	guards map[transition][]guard
+	// hooks are the functions called when the fsm moves between states
+	hooks hooks
+	// recordHistory enables the history recorder
+	recordHistory bool
+	history       []Record
+	// parents maps every sub state to its parent state
+	parents map[string]string
+	// timeouts fire an action after some time in a state
+	timeouts []timeout
+	// entered is the time every state of the active path was entered
+	entered map[string]time.Time
+	clock   Clock
+	// meta and stateMeta are free form metadata of the fsm and of its states
+	meta      map[string]string
+	stateMeta map[string]map[string]string
 }
 
 // GetState gets the current state
@@ -112,6 +156,7 @@ func (f *FSM) AddState(name string) error {
 // AdTrans adds a new transition between two states
 // It validates transition name prior to add it
 // It validates transition is unique
+// In deterministic mode it validates the state and action do not lead to another state
 func (f *FSM) AddTrans(src string, des string, name string) error {
 	if !isValidName(name) {
 		return ErrInvalidName
@@ -129,6 +174,11 @@ func (f *FSM) AddTrans(src string, des string, name string) error {
 			trans.Action == name {
 			return ErrTransAlExists
 		}
+		if f.deterministic &&
+			trans.From == src &&
+			trans.Action == name {
+			return nondeterministic(src, name, trans.To, des)
+		}
 	}
 	f.adj = append(f.adj, transition{
 		From:   src,
@@ -141,6 +191,95 @@ func (f *FSM) AddTrans(src string, des string, name string) error {
 	return nil
 }
 
+// GetInitial gets the initial state, the one given to Init
+func (f *FSM) GetInitial() string {
+	return f.initial
+}
+
+// AddFinal marks the given state as a final state
+// It validates state exists prior to mark it
+func (f *FSM) AddFinal(state string) error {
+	if ok := f.states[state]; !ok {
+		return ErrStateNotFound
+	}
+	if f.final == nil {
+		f.final = make(map[string]bool)
+	}
+	f.final[state] = true
+	return nil
+}
+
+// IsFinal reports whether the given state is a final state
+func (f *FSM) IsFinal(state string) bool {
+	return f.final[state]
+}
+
+// Terminated reports whether the current state is a final state
+func (f *FSM) Terminated() bool {
+	return f.final[f.current]
+}
+
+// SetAllowFinalExit allows or rejects the transitions out of a final state, they are rejected by default
+func (f *FSM) SetAllowFinalExit(allow bool) {
+	f.allowFinalExit = allow
+}
+
+// canLeave validates the fsm is allowed to leave the current state
+func (f *FSM) canLeave() error {
+	if f.Terminated() && !f.allowFinalExit {
+		return ErrFinalState
+	}
+	return nil
+}
+
+// States returns the names of the states, sorted
+func (f *FSM) States() []string {
+	return f.stateNames()
+}
+
+// Transitions returns the transitions as from, to and action, in the order they were added
+func (f *FSM) Transitions() [][3]string {
+	trans := make([][3]string, 0, len(f.adj))
+	for _, adj := range f.adj {
+		trans = append(trans, [3]string{adj.From, adj.To, adj.Action})
+	}
+	return trans
+}
+
+// Actions returns the actions of the transitions, sorted
+func (f *FSM) Actions() []string {
+	var actions []string
+	seen := make(map[string]bool)
+	for _, adj := range f.adj {
+		if !seen[adj.Action] {
+			seen[adj.Action] = true
+			actions = append(actions, adj.Action)
+		}
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// stateNames returns the names of the states, sorted
+func (f *FSM) stateNames() []string {
+	names := make([]string, 0, len(f.states))
+	for name := range f.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// finalNames returns the names of the final states, sorted
+func (f *FSM) finalNames() []string {
+	var names []string
+	for name := range f.final {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // GetTrans returns the transitions string representation of fsm
 func (f *FSM) GetTrans() string {
 	builder := strings.Builder{}
@@ -151,39 +290,61 @@ func (f *FSM) GetTrans() string {
 	return builder.String()
 }
 
-// Init set the current state to the given state
+// Init set the current state to the given state, it also becomes the initial state
 // It validates state exists prior to set it to current
 func (f *FSM) Init(state string) error {
-	if ok := f.states[state]; !ok {
-		return ErrStateNotFound
-	}
-	f.current = state
-	return nil
+	return f.InitContext(context.Background(), state)
 }
 
+// Exec moves the fsm to the given state using the given action
+// It validates the transition exists and all its guards pass prior to move
+// It fails with ErrFinalState when the transition leaves a final state, unless SetAllowFinalExit was called
+// Once the guards pass, the hooks are called in this order:
+// before all, before action, exit previous state, (move), enter new state, after action, after all
+// With sub states, every state left is exited innermost first and every state entered is entered outermost first
+// The callback, if any, is called last
 func (f *FSM) Exec(action string, des string, callback func(previous string, new string, action string)) error {
+	return f.ExecContext(context.Background(), action, des, Hook(callback).withContext())
+}
+
+// Fire executes the given action from the current state
+// It resolves the destination state from the transitions of the current state
+// It fails when none or several transitions match the action
+func (f *FSM) Fire(action string, callback func(previous string, new string, action string)) error {
+	return f.FireContext(context.Background(), action, Hook(callback).withContext())
+}
+
+// resolve returns the destination of the given action from the current state
+func (f *FSM) resolve(action string) (string, error) {
 	if !f.isInternal {
 		if f.state.current != ready {
-			return ErrNotReady
+			return "", ErrNotReady
 		}
 	}
-	if ok := f.states[des]; !ok {
-		return ErrStateNotFound
+	targets := f.targets(f.current, action)
+	if len(targets) == 0 {
+		return "", ErrExecNotAllowed
 	}
+	// a terminated fsm only fails for the actions that would leave its state
+	if err := f.canLeave(); err != nil {
+		return "", err
+	}
+	if len(targets) == 1 {
+		return targets[0], nil
+	}
+	return "", fmt.Errorf("%w: %v from %v leads to %v", ErrAmbiguousTrans, action, f.current, strings.Join(targets, ", "))
+}
 
+// findTrans looks for the transition between two states with the given action
+func (f *FSM) findTrans(src string, des string, action string) (transition, bool) {
 	for _, adj := range f.adj {
-		if adj.From == f.current &&
+		if adj.From == src &&
 			adj.To == des &&
 			adj.Action == action {
-			previous := f.current
-			f.current = des
-			if callback != nil {
-				callback(previous, f.current, action)
-				return nil
-			}
+			return adj, true
 		}
 	}
-	return ErrExecNotAllowed
+	return transition{}, false
 }
 
 func isValidName(name string) bool {
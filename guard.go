@@ -0,0 +1,71 @@
+package fsm
+
+import "fmt"
+
+// Guard is a predicate evaluated before a transition is executed.
+// The transition is executed only when every guard returns true.
+type Guard func(previous string, new string, action string) bool
+
+type guard struct {
+	name string
+	fn   Guard
+}
+
+// GuardError is returned by Exec when a guard rejects a transition.
+// It wraps ErrGuardRejected, so errors.Is(err, ErrGuardRejected) holds.
+type GuardError struct {
+	Guard  string
+	From   string
+	To     string
+	Action string
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("guard %v rejected %v (%v) -> (%v)", e.Guard, e.Action, e.From, e.To)
+}
+
+func (e *GuardError) Unwrap() error {
+	return ErrGuardRejected
+}
+
+// AddGuard adds a named guard to an existing transition
+// It validates guard name prior to add it
+// It validates guard is unique for the transition and not nil
+// Guards are evaluated in the order they were added
+func (f *FSM) AddGuard(src string, des string, action string, name string, g Guard) error {
+	if !isValidName(name) {
+		return ErrInvalidName
+	}
+	t, ok := f.findTrans(src, des, action)
+	if !ok {
+		return ErrTransNotFound
+	}
+	if g == nil {
+		return ErrInvalidGuard
+	}
+	for _, gd := range f.guards[t] {
+		if gd.name == name {
+			return ErrGuardAlExists
+		}
+	}
+	if f.guards == nil {
+		f.guards = make(map[transition][]guard)
+	}
+	f.guards[t] = append(f.guards[t], guard{name: name, fn: g})
+	return nil
+}
+
+// checkGuards evaluates the guards of the transition, the first one to fail is reported
+func (f *FSM) checkGuards(t transition) error {
+	for _, gd := range f.guards[t] {
+		if !gd.fn(t.From, t.To, t.Action) {
+			return &GuardError{
+				Guard:  gd.name,
+				From:   t.From,
+				To:     t.To,
+				Action: t.Action,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGuards(t *testing.T) {
+	var err error
+	const TRIAL = "TRIAL"
+	const BASIC = "BASIC"
+	const UPGRADE = "UPGRADE"
+
+	f, err := New("Guarded Plan", [][3]string{
+		{TRIAL, BASIC, UPGRADE},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init(TRIAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified := false
+	calls := 0
+	err = f.AddGuard(TRIAL, BASIC, UPGRADE, "ACCOUNT_EXISTS", func(pre string, cur string, action string) bool {
+		calls++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.AddGuard(TRIAL, BASIC, UPGRADE, "PAYMENT_VERIFIED", func(pre string, cur string, action string) bool {
+		return verified
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Exec(UPGRADE, BASIC, nil)
+	var gerr *GuardError
+	if !errors.As(err, &gerr) {
+		t.Fatalf("should errored guard rejected, got %v", err)
+	}
+	if gerr.Guard != "PAYMENT_VERIFIED" {
+		t.Errorf("wrong guard reported: %v", gerr.Guard)
+	}
+	if !errors.Is(err, ErrGuardRejected) {
+		t.Errorf("should wrap ErrGuardRejected")
+	}
+	if f.GetState() != TRIAL {
+		t.Errorf("state should not change when a guard fails")
+	}
+
+	verified = true
+	err = f.Exec(UPGRADE, BASIC, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GetState() != BASIC {
+		t.Errorf("state should be BASIC")
+	}
+	if calls != 2 {
+		t.Errorf("guard should be evaluated on every exec, got %v calls", calls)
+	}
+}
+
+func TestAddGuardErrors(t *testing.T) {
+	f, err := New("Guarded Plan", [][3]string{
+		{"TRIAL", "BASIC", "UPGRADE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass := func(pre string, cur string, action string) bool { return true }
+
+	err = f.AddGuard("TRIAL", "BASIC", "UPGRADE", "invalid name", pass)
+	if err != ErrInvalidName {
+		t.Errorf("should errored invalid name")
+	}
+	err = f.AddGuard("BASIC", "TRIAL", "UPGRADE", "PAID", pass)
+	if err != ErrTransNotFound {
+		t.Errorf("should errored transition not found")
+	}
+	err = f.AddGuard("TRIAL", "BASIC", "UPGRADE", "PAID", nil)
+	if err != ErrInvalidGuard {
+		t.Errorf("should errored invalid guard")
+	}
+	err = f.AddGuard("TRIAL", "BASIC", "UPGRADE", "PAID", pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.AddGuard("TRIAL", "BASIC", "UPGRADE", "PAID", pass)
+	if err != ErrGuardAlExists {
+		t.Errorf("should errored guard already exists")
+	}
+}
@@ -0,0 +1,107 @@
+package fsm
+
+// AddSubState adds a new state into the fsm as a child of the given parent state
+// It validates state name prior to add it
+// It validates state is unique and parent exists
+// Transitions, guards and hooks defined on the parent apply to all its descendants
+func (f *FSM) AddSubState(parent string, name string) error {
+	if ok := f.states[parent]; !ok {
+		return ErrStateNotFound
+	}
+	if err := f.AddState(name); err != nil {
+		return err
+	}
+	if f.parents == nil {
+		f.parents = make(map[string]string)
+	}
+	f.parents[name] = parent
+	return nil
+}
+
+// GetParent returns the parent of the given state, empty for top level states
+func (f *FSM) GetParent(state string) string {
+	return f.parents[state]
+}
+
+// GetPath returns the active path, from the top level state down to the current state
+func (f *FSM) GetPath() []string {
+	return f.path(f.current)
+}
+
+// IsIn reports whether the current state is the given state or one of its descendants
+func (f *FSM) IsIn(state string) bool {
+	for s := f.current; s != ""; s = f.parents[s] {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// children returns the sorted children of every state, top level states are the children of ""
+func (f *FSM) children() map[string][]string {
+	children := make(map[string][]string)
+	for _, name := range f.stateNames() {
+		parent := f.parents[name]
+		children[parent] = append(children[parent], name)
+	}
+	return children
+}
+
+// path returns the ancestors of the state, top level first, followed by the state
+func (f *FSM) path(state string) []string {
+	if state == "" {
+		return nil
+	}
+	var path []string
+	for s := state; s != ""; s = f.parents[s] {
+		path = append([]string{s}, path...)
+	}
+	return path
+}
+
+// lookupTrans looks for the transition from the state or its closest ancestor
+func (f *FSM) lookupTrans(state string, des string, action string) (transition, bool) {
+	for s := state; s != ""; s = f.parents[s] {
+		if t, ok := f.findTrans(s, des, action); ok {
+			return t, true
+		}
+	}
+	return transition{}, false
+}
+
+// targets returns the destinations of the action from the state or its closest ancestor that defines it
+func (f *FSM) targets(state string, action string) []string {
+	for s := state; s != ""; s = f.parents[s] {
+		var targets []string
+		for _, adj := range f.adj {
+			if adj.From == s && adj.Action == action {
+				targets = append(targets, adj.To)
+			}
+		}
+		if len(targets) > 0 {
+			return targets
+		}
+	}
+	return nil
+}
+
+// exitEnter returns the states left, innermost first, and the states entered, outermost first,
+// when moving between the two states
+// A transition to the same state leaves and enters it again
+func (f *FSM) exitEnter(src string, des string) ([]string, []string) {
+	from := f.path(src)
+	to := f.path(des)
+	common := 0
+	for common < len(from) && common < len(to) && from[common] == to[common] {
+		common++
+	}
+	if src == des && common > 0 {
+		common--
+	}
+	var exit []string
+	for i := len(from) - 1; i >= common; i-- {
+		exit = append(exit, from[i])
+	}
+	return exit, to[common:]
+}
@@ -0,0 +1,119 @@
+package fsm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSubStates(t *testing.T) {
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tenant.State
+
+	if err := f.AddSubState("MISSING", "CHILD"); err != ErrStateNotFound {
+		t.Errorf("should errored state not found, got %v", err)
+	}
+	if err := f.AddSubState("ACTIVE", "TRIAL"); err != ErrStateAlExists {
+		t.Errorf("should errored state already exists, got %v", err)
+	}
+
+	for _, start := range []string{"TRIAL", "BASIC", "GOLD"} {
+		if err := f.Init(start); err != nil {
+			t.Fatal(err)
+		}
+		if !f.IsIn("ACTIVE") {
+			t.Errorf("%v should be in ACTIVE", start)
+		}
+		if err := f.Fire("SUSPEND", nil); err != nil {
+			t.Fatalf("%v should inherit SUSPEND: %v", start, err)
+		}
+		if f.GetState() != "SUSPENDED" {
+			t.Errorf("state should be SUSPENDED, got %v", f.GetState())
+		}
+	}
+
+	if err := f.Init("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(f.GetPath(), "/"); got != "ACTIVE/PREMIUM/GOLD" {
+		t.Errorf("wrong path: %v", got)
+	}
+	if f.GetParent("GOLD") != "PREMIUM" || f.GetParent("ACTIVE") != "" {
+		t.Errorf("wrong parents")
+	}
+	if f.IsIn("BASIC") {
+		t.Errorf("GOLD should not be in BASIC")
+	}
+}
+
+func TestSubStatesHooks(t *testing.T) {
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tenant.State
+	var calls []string
+	for _, s := range []string{"ACTIVE", "PREMIUM", "GOLD", "SUSPENDED", "BASIC"} {
+		state := s
+		if err := f.OnExit(state, func(pre string, cur string, action string) {
+			calls = append(calls, "EXIT_"+state)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.OnEnter(state, func(pre string, cur string, action string) {
+			calls = append(calls, "ENTER_"+state)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Init("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Exec("SUSPEND", "SUSPENDED", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Exec("RESUME", "BASIC", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Exec("UPGRADE", "PREMIUM", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "EXIT_GOLD EXIT_PREMIUM EXIT_ACTIVE ENTER_SUSPENDED " +
+		"EXIT_SUSPENDED ENTER_ACTIVE ENTER_BASIC " +
+		"EXIT_BASIC ENTER_PREMIUM"
+	if got := strings.Join(calls, " "); got != expected {
+		t.Errorf("wrong hooks:\n got: %v\nwant: %v", got, expected)
+	}
+}
+
+func TestSubStatesMarshalUnmarshal(t *testing.T) {
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tenant.State
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f2 FSM
+	if err = json.Unmarshal(b, &f2); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(f2.GetPath(), "/"); got != "ACTIVE/TRIAL" {
+		t.Errorf("wrong path after unmarshal: %v", got)
+	}
+	if err = f2.Fire("SUSPEND", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b = []byte(`{"name":"Cycle","current":"A","states":["A","B"],"transitions":[{"from":"A","to":"B","action":"GO"}],"parents":{"A":"B","B":"A"}}`)
+	var f3 FSM
+	if err = json.Unmarshal(b, &f3); err != ErrInvalidParent {
+		t.Errorf("should errored invalid parent, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package fsm
+
+import (
+	"context"
+	"time"
+)
+
+// Record is an entry of the fsm history, it describes one executed transition
+type Record struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Action   string            `json:"action"`
+	At       time.Time         `json:"at"`
+	Actor    string            `json:"actor,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type actorKey struct{}
+type metadataKey struct{}
+
+// WithActor returns a context carrying the actor recorded in the history by ExecContext
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// WithMetadata returns a context carrying a metadata entry recorded in the history by ExecContext
+// Entries already carried by ctx are kept
+func WithMetadata(ctx context.Context, key string, value string) context.Context {
+	md := make(map[string]string)
+	if prev, ok := ctx.Value(metadataKey{}).(map[string]string); ok {
+		for k, v := range prev {
+			md[k] = v
+		}
+	}
+	md[key] = value
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// EnableHistory makes the fsm record every executed transition
+func (f *FSM) EnableHistory() {
+	f.recordHistory = true
+}
+
+// History returns the recorded transitions, oldest first
+func (f *FSM) History() []Record {
+	history := make([]Record, len(f.history))
+	copy(history, f.history)
+	return history
+}
+
+// record appends the transition to the history when it is enabled
+func (f *FSM) record(ctx context.Context, at time.Time, from string, to string, action string) {
+	if !f.recordHistory {
+		return
+	}
+	r := Record{
+		From:   from,
+		To:     to,
+		Action: action,
+		At:     at,
+	}
+	if actor, ok := ctx.Value(actorKey{}).(string); ok {
+		r.Actor = actor
+	}
+	if md, ok := ctx.Value(metadataKey{}).(map[string]string); ok {
+		r.Metadata = md
+	}
+	f.history = append(f.history, r)
+}
@@ -0,0 +1,109 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	f, err := New("Transfer", [][3]string{
+		{"PENDING", "SENT", "SEND"},
+		{"SENT", "SETTLED", "SETTLE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Exec("SEND", "SENT", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.History()) != 0 {
+		t.Errorf("history should be disabled by default")
+	}
+
+	f.EnableHistory()
+	ctx := WithActor(context.Background(), "alice")
+	ctx = WithMetadata(ctx, "ticket", "T-1")
+	ctx = WithMetadata(ctx, "reason", "batch")
+	err = f.ExecContext(ctx, "SETTLE", "SETTLED", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := f.History()
+	if len(history) != 1 {
+		t.Fatalf("history should have 1 record, got %v", len(history))
+	}
+	r := history[0]
+	if r.From != "SENT" || r.To != "SETTLED" || r.Action != "SETTLE" {
+		t.Errorf("wrong record: %+v", r)
+	}
+	if r.At.IsZero() {
+		t.Errorf("record should have a timestamp")
+	}
+	if r.Actor != "alice" {
+		t.Errorf("wrong actor: %v", r.Actor)
+	}
+	if r.Metadata["ticket"] != "T-1" || r.Metadata["reason"] != "batch" {
+		t.Errorf("wrong metadata: %v", r.Metadata)
+	}
+}
+
+func TestHistoryMarshalUnmarshal(t *testing.T) {
+	f, err := New("Transfer", [][3]string{
+		{"PENDING", "SENT", "SEND"},
+		{"SENT", "SETTLED", "SETTLE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.EnableHistory()
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var empty FSM
+	if err = json.Unmarshal(b, &empty); err != nil {
+		t.Fatal(err)
+	}
+	if !empty.recordHistory {
+		t.Errorf("history should stay enabled")
+	}
+
+	err = f.ExecContext(WithActor(context.Background(), "bob"), "SEND", "SENT", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f2 FSM
+	if err = json.Unmarshal(b, &f2); err != nil {
+		t.Fatal(err)
+	}
+	if err = f2.Exec("SETTLE", "SETTLED", nil); err != nil {
+		t.Fatal(err)
+	}
+	history := f2.History()
+	if len(history) != 2 {
+		t.Fatalf("history should have 2 records, got %v", len(history))
+	}
+	if history[0].Actor != "bob" || !history[0].At.Equal(f.History()[0].At) {
+		t.Errorf("wrong record after unmarshal: %+v", history[0])
+	}
+	if history[1].From != "SENT" || history[1].To != "SETTLED" {
+		t.Errorf("wrong record: %+v", history[1])
+	}
+}
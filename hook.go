@@ -0,0 +1,149 @@
+package fsm
+
+import "context"
+
+// Hook is a function called by Exec when the fsm moves between states
+type Hook func(previous string, new string, action string)
+
+// ContextHook is a Hook that receives the context given to ExecContext
+type ContextHook func(ctx context.Context, previous string, new string, action string)
+
+// withContext adapts the hook to a ContextHook, a nil hook stays nil
+func (h Hook) withContext() ContextHook {
+	if h == nil {
+		return nil
+	}
+	return func(ctx context.Context, previous string, new string, action string) {
+		h(previous, new, action)
+	}
+}
+
+// hooks holds the hooks registered on a fsm
+type hooks struct {
+	enter     map[string][]ContextHook
+	exit      map[string][]ContextHook
+	before    map[string][]ContextHook
+	after     map[string][]ContextHook
+	beforeAll []ContextHook
+	afterAll  []ContextHook
+}
+
+// OnEnter registers a hook called every time the fsm enters the given state
+// It validates state exists and the hook is not nil prior to register it
+func (f *FSM) OnEnter(state string, h Hook) error {
+	return f.OnEnterContext(state, h.withContext())
+}
+
+// OnEnterContext is like OnEnter but the hook receives the context
+func (f *FSM) OnEnterContext(state string, h ContextHook) error {
+	if ok := f.states[state]; !ok {
+		return ErrStateNotFound
+	}
+	if h == nil {
+		return ErrInvalidHook
+	}
+	if f.hooks.enter == nil {
+		f.hooks.enter = make(map[string][]ContextHook)
+	}
+	f.hooks.enter[state] = append(f.hooks.enter[state], h)
+	return nil
+}
+
+// OnExit registers a hook called every time the fsm leaves the given state
+// It validates state exists and the hook is not nil prior to register it
+func (f *FSM) OnExit(state string, h Hook) error {
+	return f.OnExitContext(state, h.withContext())
+}
+
+// OnExitContext is like OnExit but the hook receives the context
+func (f *FSM) OnExitContext(state string, h ContextHook) error {
+	if ok := f.states[state]; !ok {
+		return ErrStateNotFound
+	}
+	if h == nil {
+		return ErrInvalidHook
+	}
+	if f.hooks.exit == nil {
+		f.hooks.exit = make(map[string][]ContextHook)
+	}
+	f.hooks.exit[state] = append(f.hooks.exit[state], h)
+	return nil
+}
+
+// OnBefore registers a hook called before the fsm executes the given action
+// It validates action name and the hook is not nil prior to register it
+func (f *FSM) OnBefore(action string, h Hook) error {
+	return f.OnBeforeContext(action, h.withContext())
+}
+
+// OnBeforeContext is like OnBefore but the hook receives the context
+func (f *FSM) OnBeforeContext(action string, h ContextHook) error {
+	if !isValidName(action) {
+		return ErrInvalidName
+	}
+	if h == nil {
+		return ErrInvalidHook
+	}
+	if f.hooks.before == nil {
+		f.hooks.before = make(map[string][]ContextHook)
+	}
+	f.hooks.before[action] = append(f.hooks.before[action], h)
+	return nil
+}
+
+// OnAfter registers a hook called after the fsm executes the given action
+// It validates action name and the hook is not nil prior to register it
+func (f *FSM) OnAfter(action string, h Hook) error {
+	return f.OnAfterContext(action, h.withContext())
+}
+
+// OnAfterContext is like OnAfter but the hook receives the context
+func (f *FSM) OnAfterContext(action string, h ContextHook) error {
+	if !isValidName(action) {
+		return ErrInvalidName
+	}
+	if h == nil {
+		return ErrInvalidHook
+	}
+	if f.hooks.after == nil {
+		f.hooks.after = make(map[string][]ContextHook)
+	}
+	f.hooks.after[action] = append(f.hooks.after[action], h)
+	return nil
+}
+
+// OnBeforeAll registers a hook called before the fsm executes any action
+// It validates the hook is not nil prior to register it
+func (f *FSM) OnBeforeAll(h Hook) error {
+	return f.OnBeforeAllContext(h.withContext())
+}
+
+// OnBeforeAllContext is like OnBeforeAll but the hook receives the context
+func (f *FSM) OnBeforeAllContext(h ContextHook) error {
+	if h == nil {
+		return ErrInvalidHook
+	}
+	f.hooks.beforeAll = append(f.hooks.beforeAll, h)
+	return nil
+}
+
+// OnAfterAll registers a hook called after the fsm executes any action
+// It validates the hook is not nil prior to register it
+func (f *FSM) OnAfterAll(h Hook) error {
+	return f.OnAfterAllContext(h.withContext())
+}
+
+// OnAfterAllContext is like OnAfterAll but the hook receives the context
+func (f *FSM) OnAfterAllContext(h ContextHook) error {
+	if h == nil {
+		return ErrInvalidHook
+	}
+	f.hooks.afterAll = append(f.hooks.afterAll, h)
+	return nil
+}
+
+func runHooks(ctx context.Context, hs []ContextHook, previous string, new string, action string) {
+	for _, h := range hs {
+		h(ctx, previous, new, action)
+	}
+}
@@ -0,0 +1,135 @@
+package fsm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHooksOrder(t *testing.T) {
+	const TRIAL = "TRIAL"
+	const BASIC = "BASIC"
+	const UPGRADE = "UPGRADE"
+
+	f, err := New("Hooked Plan", [][3]string{
+		{TRIAL, BASIC, UPGRADE},
+		{BASIC, TRIAL, "DOWNGRADE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init(TRIAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	record := func(name string) Hook {
+		return func(pre string, cur string, action string) {
+			calls = append(calls, name)
+		}
+	}
+
+	f.OnAfterAll(record("AFTER_ALL"))
+	f.OnBeforeAll(record("BEFORE_ALL"))
+	if err = f.OnEnter(BASIC, record("ENTER_BASIC")); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.OnExit(TRIAL, record("EXIT_TRIAL")); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.OnBefore(UPGRADE, record("BEFORE_UPGRADE")); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.OnAfter(UPGRADE, record("AFTER_UPGRADE")); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.OnEnter(TRIAL, record("ENTER_TRIAL")); err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Exec(UPGRADE, BASIC, func(pre string, cur string, action string) {
+		if f.GetState() != BASIC {
+			t.Errorf("callback should be called after the fsm moved")
+		}
+		calls = append(calls, "CALLBACK")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "BEFORE_ALL BEFORE_UPGRADE EXIT_TRIAL ENTER_BASIC AFTER_UPGRADE AFTER_ALL CALLBACK"
+	if got := strings.Join(calls, " "); got != expected {
+		t.Errorf("wrong hooks order:\n got: %v\nwant: %v", got, expected)
+	}
+}
+
+func TestHooksNotCalledOnRejectedExec(t *testing.T) {
+	f, err := New("Hooked Plan", [][3]string{
+		{"TRIAL", "BASIC", "UPGRADE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	f.OnBeforeAll(func(pre string, cur string, action string) {
+		called = true
+	})
+	err = f.AddGuard("TRIAL", "BASIC", "UPGRADE", "NEVER", func(pre string, cur string, action string) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Exec("UPGRADE", "BASIC", nil); err == nil {
+		t.Errorf("should errored guard rejected")
+	}
+	if err = f.Exec("DOWNGRADE", "TRIAL", nil); err == nil {
+		t.Errorf("should errored exec not allowed")
+	}
+	if called {
+		t.Errorf("hooks should not be called when exec fails")
+	}
+}
+
+func TestHooksErrors(t *testing.T) {
+	f := NewFSM("BASIC")
+	noop := func(pre string, cur string, action string) {}
+	if err := f.OnEnter("MISSING", noop); err != ErrStateNotFound {
+		t.Errorf("should errored state not found")
+	}
+	if err := f.OnExit("MISSING", noop); err != ErrStateNotFound {
+		t.Errorf("should errored state not found")
+	}
+	if err := f.OnBefore("bad action", noop); err != ErrInvalidName {
+		t.Errorf("should errored invalid name")
+	}
+	if err := f.OnAfter("bad action", noop); err != ErrInvalidName {
+		t.Errorf("should errored invalid name")
+	}
+
+	if err := f.AddState("TRIAL"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.OnEnter("TRIAL", nil); err != ErrInvalidHook {
+		t.Errorf("should errored invalid hook, got %v", err)
+	}
+	if err := f.OnExitContext("TRIAL", nil); err != ErrInvalidHook {
+		t.Errorf("should errored invalid hook, got %v", err)
+	}
+	if err := f.OnBefore("UPGRADE", nil); err != ErrInvalidHook {
+		t.Errorf("should errored invalid hook, got %v", err)
+	}
+	if err := f.OnAfter("UPGRADE", nil); err != ErrInvalidHook {
+		t.Errorf("should errored invalid hook, got %v", err)
+	}
+	if err := f.OnBeforeAll(nil); err != ErrInvalidHook {
+		t.Errorf("should errored invalid hook, got %v", err)
+	}
+	if err := f.OnAfterAllContext(nil); err != ErrInvalidHook {
+		t.Errorf("should errored invalid hook, got %v", err)
+	}
+}
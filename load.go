@@ -0,0 +1,369 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDefinition = errors.New("invalid definition")
+
+// DefinitionError is an error found in a definition file, it wraps the error found at that line
+type DefinitionError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *DefinitionError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%v:%v: %v", e.File, e.Line, e.Err)
+}
+
+func (e *DefinitionError) Unwrap() error {
+	return e.Err
+}
+
+// LoadFile creates a fsm from a definition file, YAML for .yaml and .yml files, TOML for .toml files,
+// JSON for .json files, SCXML for .scxml files and Mermaid for .mmd files
+// Errors report the file and the line
+func LoadFile(path string) (*FSM, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var f *FSM
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		f, err = ParseYAML(file)
+	case ".toml":
+		f, err = ParseTOML(file)
+	case ".json":
+		f = NewFSM("")
+		err = json.NewDecoder(file).Decode(f)
+	case ".scxml":
+		f, err = ParseSCXML(file)
+	case ".mmd":
+		f, err = ParseMermaid(file)
+	default:
+		return nil, fmt.Errorf("%w: unknown extension of %v", ErrInvalidDefinition, path)
+	}
+	var derr *DefinitionError
+	if errors.As(err, &derr) {
+		derr.File = path
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+type nodeKind int
+
+const (
+	nullNode nodeKind = iota
+	scalarNode
+	mapNode
+	seqNode
+)
+
+// node is a value of a definition file, YAML and TOML are both parsed into nodes
+type node struct {
+	line  int
+	kind  nodeKind
+	value string
+	keys  []string
+	vals  []*node
+	items []*node
+}
+
+func (n *node) get(key string) *node {
+	for i, k := range n.keys {
+		if k == key {
+			return n.vals[i]
+		}
+	}
+	return nil
+}
+
+func (n *node) set(key string, val *node) error {
+	if n.get(key) != nil {
+		return fmt.Errorf("%w: duplicated key %v", ErrInvalidDefinition, key)
+	}
+	n.keys = append(n.keys, key)
+	n.vals = append(n.vals, val)
+	return nil
+}
+
+func defError(line int, format string, args ...interface{}) error {
+	return &DefinitionError{Line: line, Err: fmt.Errorf(format, args...)}
+}
+
+// scalar returns the value of a scalar node
+func (n *node) scalar(what string) (string, error) {
+	if n.kind != scalarNode {
+		return "", defError(n.line, "%w: %v must be a value", ErrInvalidDefinition, what)
+	}
+	return n.value, nil
+}
+
+// list returns the values of a sequence of scalars, a single scalar is a list of one
+func (n *node) list(what string) ([]string, error) {
+	if n.kind == scalarNode {
+		return []string{n.value}, nil
+	}
+	if n.kind == nullNode {
+		return nil, nil
+	}
+	if n.kind != seqNode {
+		return nil, defError(n.line, "%w: %v must be a list", ErrInvalidDefinition, what)
+	}
+	var values []string
+	for _, item := range n.items {
+		v, err := item.scalar(what)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// fields returns the scalar fields of a mapping node, a sequence gives them in order
+func (n *node) fields(what string, names ...string) ([]string, error) {
+	values := make([]string, len(names))
+	switch n.kind {
+	case seqNode:
+		if len(n.items) != len(names) {
+			return nil, defError(n.line, "%w: %v must be [%v]", ErrInvalidDefinition, what, strings.Join(names, ", "))
+		}
+		for i, item := range n.items {
+			v, err := item.scalar(names[i])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+	case mapNode:
+		for i, k := range n.keys {
+			found := false
+			for j, name := range names {
+				if k == name {
+					v, err := n.vals[i].scalar(name)
+					if err != nil {
+						return nil, err
+					}
+					values[j] = v
+					found = true
+				}
+			}
+			if !found {
+				return nil, defError(n.vals[i].line, "%w: unknown key %v in %v", ErrInvalidDefinition, k, what)
+			}
+		}
+		for j, name := range names {
+			if values[j] == "" {
+				return nil, defError(n.line, "%w: %v without %v", ErrInvalidDefinition, what, name)
+			}
+		}
+	default:
+		return nil, defError(n.line, "%w: invalid %v", ErrInvalidDefinition, what)
+	}
+	return values, nil
+}
+
+// metadata returns the entries of a mapping of scalars
+func (n *node) metadata() (map[string]string, error) {
+	if n.kind == nullNode {
+		return nil, nil
+	}
+	if n.kind != mapNode {
+		return nil, defError(n.line, "%w: metadata must be a mapping", ErrInvalidDefinition)
+	}
+	meta := make(map[string]string)
+	for i, k := range n.keys {
+		v, err := n.vals[i].scalar("metadata " + k)
+		if err != nil {
+			return nil, err
+		}
+		meta[k] = v
+	}
+	return meta, nil
+}
+
+// build creates a fsm from the root node of a definition file
+func build(root *node) (*FSM, error) {
+	if root.kind != mapNode {
+		return nil, defError(root.line, "%w: definition must be a mapping", ErrInvalidDefinition)
+	}
+	f := NewFSM("")
+	for i, k := range root.keys {
+		switch k {
+		case "name", "initial", "final", "metadata", "states", "transitions", "timeouts":
+		default:
+			return nil, defError(root.vals[i].line, "%w: unknown key %v", ErrInvalidDefinition, k)
+		}
+	}
+	if n := root.get("name"); n != nil {
+		name, err := n.scalar("name")
+		if err != nil {
+			return nil, err
+		}
+		f.Name = name
+	}
+	if n := root.get("metadata"); n != nil {
+		meta, err := n.metadata()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range meta {
+			f.SetMeta(k, v)
+		}
+	}
+	if n := root.get("states"); n != nil {
+		if err := buildStates(f, n); err != nil {
+			return nil, err
+		}
+	}
+	if n := root.get("transitions"); n != nil {
+		if n.kind != seqNode {
+			return nil, defError(n.line, "%w: transitions must be a list", ErrInvalidDefinition)
+		}
+		for _, item := range n.items {
+			v, err := item.fields("transition", "from", "to", "action")
+			if err != nil {
+				return nil, err
+			}
+			if err = f.AddTrans(v[0], v[1], v[2]); err != nil {
+				return nil, defError(item.line, "transition %v (%v) -> (%v): %w", v[2], v[0], v[1], err)
+			}
+		}
+	}
+	if n := root.get("final"); n != nil {
+		states, err := n.list("final")
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range states {
+			if err = f.AddFinal(s); err != nil {
+				return nil, defError(n.line, "final state %v: %w", s, err)
+			}
+		}
+	}
+	if n := root.get("timeouts"); n != nil {
+		if n.kind != seqNode {
+			return nil, defError(n.line, "%w: timeouts must be a list", ErrInvalidDefinition)
+		}
+		for _, item := range n.items {
+			v, err := item.fields("timeout", "state", "after", "action")
+			if err != nil {
+				return nil, err
+			}
+			after, err := time.ParseDuration(v[1])
+			if err != nil {
+				return nil, defError(item.line, "timeout %v of %v: %w", v[2], v[0], ErrInvalidTimeout)
+			}
+			if err = f.AddTimeout(v[0], after, v[2]); err != nil {
+				return nil, defError(item.line, "timeout %v of %v: %w", v[2], v[0], err)
+			}
+		}
+	}
+	if n := root.get("initial"); n != nil {
+		initial, err := n.scalar("initial")
+		if err != nil {
+			return nil, err
+		}
+		if err = f.Init(initial); err != nil {
+			return nil, defError(n.line, "initial state %v: %w", initial, err)
+		}
+	}
+	return f, nil
+}
+
+// buildStates adds the states, a list of names or a mapping of names to their parent and metadata
+// A parent can be declared after its sub states
+func buildStates(f *FSM, n *node) error {
+	type stateDef struct {
+		name   string
+		parent string
+		meta   map[string]string
+		line   int
+	}
+	var defs []stateDef
+	switch n.kind {
+	case seqNode:
+		for _, item := range n.items {
+			name, err := item.scalar("state")
+			if err != nil {
+				return err
+			}
+			defs = append(defs, stateDef{name: name, line: item.line})
+		}
+	case mapNode:
+		for i, name := range n.keys {
+			def := stateDef{name: name, line: n.vals[i].line}
+			val := n.vals[i]
+			switch val.kind {
+			case nullNode:
+			case mapNode:
+				for j, k := range val.keys {
+					var err error
+					switch k {
+					case "parent":
+						def.parent, err = val.vals[j].scalar("parent")
+					case "metadata":
+						def.meta, err = val.vals[j].metadata()
+					default:
+						err = defError(val.vals[j].line, "%w: unknown key %v in state %v", ErrInvalidDefinition, k, name)
+					}
+					if err != nil {
+						return err
+					}
+				}
+			default:
+				return defError(val.line, "%w: state %v must be a mapping", ErrInvalidDefinition, name)
+			}
+			defs = append(defs, def)
+		}
+	default:
+		return defError(n.line, "%w: states must be a list or a mapping", ErrInvalidDefinition)
+	}
+
+	for len(defs) > 0 {
+		var pending []stateDef
+		for _, def := range defs {
+			var err error
+			switch {
+			case def.parent == "":
+				err = f.AddState(def.name)
+			case f.states[def.parent]:
+				err = f.AddSubState(def.parent, def.name)
+			default:
+				pending = append(pending, def)
+				continue
+			}
+			if err != nil {
+				return defError(def.line, "state %v: %w", def.name, err)
+			}
+			for k, v := range def.meta {
+				if err = f.SetStateMeta(def.name, k, v); err != nil {
+					return defError(def.line, "state %v: %w", def.name, err)
+				}
+			}
+		}
+		if len(pending) == len(defs) {
+			def := pending[0]
+			return defError(def.line, "state %v: parent %v: %w", def.name, def.parent, ErrStateNotFound)
+		}
+		defs = pending
+	}
+	return nil
+}
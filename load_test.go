@@ -0,0 +1,206 @@
+package fsm
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const planYAML = `# SaaS plan
+name: SAAS Account State
+initial: TRIAL
+final: [CANCELED]
+metadata:
+  owner: billing
+  reviewed: "2021-12-13"
+states:
+  ACTIVE:
+  TRIAL:
+    parent: ACTIVE
+    metadata:
+      days: "14"
+  BASIC: {parent: ACTIVE}
+  CANCELED:
+transitions:
+  - {from: TRIAL, to: BASIC, action: UPGRATE}
+  - [ACTIVE, CANCELED, CANCEL]
+  - from: BASIC
+    to: TRIAL
+    action: RESTART
+timeouts:
+  - {state: TRIAL, after: 336h, action: CANCEL}
+`
+
+const planTOML = `# SaaS plan
+name = "SAAS Account State"
+initial = "TRIAL"
+final = ["CANCELED"]
+
+[metadata]
+owner = "billing"
+reviewed = "2021-12-13"
+
+[states.ACTIVE]
+[states.TRIAL]
+parent = "ACTIVE"
+[states.TRIAL.metadata]
+days = "14"
+[states.BASIC]
+parent = "ACTIVE"
+[states.CANCELED]
+
+[[transitions]]
+from = "TRIAL"
+to = "BASIC"
+action = "UPGRATE"
+
+[[transitions]]
+from = "ACTIVE"
+to = "CANCELED"
+action = "CANCEL"
+
+[[transitions]]
+from = "BASIC"
+to = "TRIAL"
+action = "RESTART"
+
+[[timeouts]]
+state = "TRIAL"
+after = "336h"
+action = "CANCEL"
+`
+
+func checkPlanDefinition(t *testing.T, f *FSM) {
+	t.Helper()
+	if f.Name != "SAAS Account State" || f.GetState() != "TRIAL" || f.GetInitial() != "TRIAL" {
+		t.Errorf("wrong name or initial state: %v %v", f.Name, f.GetState())
+	}
+	if !f.IsFinal("CANCELED") || f.GetParent("BASIC") != "ACTIVE" {
+		t.Errorf("wrong final states or parents")
+	}
+	if f.GetMeta()["owner"] != "billing" || f.GetMeta()["reviewed"] != "2021-12-13" || f.GetStateMeta("TRIAL")["days"] != "14" {
+		t.Errorf("wrong metadata: %v %v", f.GetMeta(), f.GetStateMeta("TRIAL"))
+	}
+	pending := f.Pending()
+	if len(pending) != 1 || pending[0].Action != "CANCEL" {
+		t.Errorf("wrong timeouts: %+v", pending)
+	}
+	if len(f.adj) != 3 {
+		t.Errorf("wrong transitions:\n%v", f.GetTrans())
+	}
+	if err := f.Fire("CANCEL", nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	f, err := ParseYAML(strings.NewReader(planYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlanDefinition(t, f)
+}
+
+func TestParseTOML(t *testing.T) {
+	f, err := ParseTOML(strings.NewReader(planTOML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlanDefinition(t, f)
+}
+
+func TestDefinitionRoundTrip(t *testing.T) {
+	f, err := ParseYAML(strings.NewReader(planYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := ParseYAML(strings.NewReader(f.YAML()))
+	if err != nil {
+		t.Fatalf("%v\n%v", err, f.YAML())
+	}
+	assertSameDefinition(t, f, fromYAML)
+	fromTOML, err := ParseTOML(strings.NewReader(f.TOML()))
+	if err != nil {
+		t.Fatalf("%v\n%v", err, f.TOML())
+	}
+	assertSameDefinition(t, f, fromTOML)
+	if fromYAML.GetStateMeta("TRIAL")["days"] != "14" || fromTOML.GetMeta()["reviewed"] != "2021-12-13" {
+		t.Errorf("metadata should round trip")
+	}
+	if fromTOML.timeouts[0].After != 336*time.Hour {
+		t.Errorf("timeouts should round trip")
+	}
+
+	plan, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err = ParseYAML(strings.NewReader(plan.State.YAML()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameDefinition(t, plan.State, fromYAML)
+}
+
+func TestDefinitionErrors(t *testing.T) {
+	tests := []struct {
+		yaml string
+		line int
+		err  error
+	}{
+		{"name: X\nstates: [A, B]\ntransitions:\n  - {from: A, to: C, action: GO}\n", 4, ErrStateNotFound},
+		{"states: [A, b]\n", 1, ErrInvalidName},
+		{"states:\n  A:\n  B: {parent: C}\n", 3, ErrStateNotFound},
+		{"states: [A]\ninitial: B\n", 2, ErrStateNotFound},
+		{"states: [A]\ncolor: blue\n", 2, ErrInvalidDefinition},
+		{"states: [A]\ntransitions:\n  - {from: A, to: A}\n", 3, ErrInvalidDefinition},
+		{"states:\n  - A\n   - B\n", 3, ErrInvalidDefinition},
+		{"states: [A]\nstates: [B]\n", 2, ErrInvalidDefinition},
+	}
+	for _, test := range tests {
+		_, err := ParseYAML(strings.NewReader(test.yaml))
+		var derr *DefinitionError
+		if !errors.As(err, &derr) || derr.Line != test.line || !errors.Is(err, test.err) {
+			t.Errorf("should errored %v at line %v, got %v", test.err, test.line, err)
+		}
+	}
+
+	_, err := ParseTOML(strings.NewReader("states = [\"A\"]\n\n[[transitions]]\nfrom = \"A\"\nto = \"B\"\naction = \"GO\"\n"))
+	var derr *DefinitionError
+	if !errors.As(err, &derr) || derr.Line != 3 || !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("should errored state not found at line 3, got %v", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{"plan.yaml": planYAML, "plan.toml": planTOML} {
+		path := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := LoadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPlanDefinition(t, f)
+	}
+
+	path := filepath.Join(dir, "broken.yml")
+	if err = ioutil.WriteFile(path, []byte("states: [A]\ninitial: B\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadFile(path)
+	if err == nil || !strings.HasPrefix(err.Error(), path+":2: ") {
+		t.Errorf("error should report file and line, got %v", err)
+	}
+}
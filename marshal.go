@@ -2,6 +2,7 @@ package fsm
 
 import (
 	"encoding/json"
+	"time"
 )
 
 func (t transition) MarshalJSON() ([]byte, error) {
@@ -33,12 +34,15 @@ func (t *transition) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func (f *FSM) MarshalJSON() ([]byte, error) {
-	var states []string
+// timeoutJSON is the representation of a timeout, the duration is written like time.Duration.String
+type timeoutJSON struct {
+	State  string `json:"state"`
+	After  string `json:"after"`
+	Action string `json:"action"`
+}
 
-	for key := range f.states {
-		states = append(states, key)
-	}
+func (f *FSM) MarshalJSON() ([]byte, error) {
+	states := f.stateNames()
 
 	trans := make([]transition, 0)
 	for _, adj := range f.adj {
@@ -49,30 +53,96 @@ func (f *FSM) MarshalJSON() ([]byte, error) {
 		}
 		trans = append(trans, newtran)
 	}
+	// history is only present when it is enabled, even if it is empty
+	var history *[]Record
+	if f.recordHistory {
+		h := append([]Record{}, f.history...)
+		history = &h
+	}
+	var timeouts []timeoutJSON
+	for _, t := range f.timeouts {
+		timeouts = append(timeouts, timeoutJSON{
+			State:  t.State,
+			After:  t.After.String(),
+			Action: t.Action,
+		})
+	}
+	// entered is only needed by the pending timers
+	var entered map[string]time.Time
+	if len(timeouts) > 0 {
+		entered = f.entered
+	}
 	return json.Marshal(&struct {
-		Name        string       `json:"name"`
-		Current     string       `json:"current"`
-		States      []string     `json:"states"`
-		Transitions []transition `json:"transitions"`
+		Name          string                       `json:"name"`
+		Version       int                          `json:"version,omitempty"`
+		Current       string                       `json:"current"`
+		Initial       string                       `json:"initial,omitempty"`
+		States        []string                     `json:"states"`
+		Final         []string                     `json:"final,omitempty"`
+		FinalExit     bool                         `json:"allow_final_exit,omitempty"`
+		Deterministic bool                         `json:"deterministic,omitempty"`
+		Transitions   []transition                 `json:"transitions"`
+		Parents       map[string]string            `json:"parents,omitempty"`
+		Timeouts      []timeoutJSON                `json:"timeouts,omitempty"`
+		Entered       map[string]time.Time         `json:"entered,omitempty"`
+		Metadata      map[string]string            `json:"metadata,omitempty"`
+		StateMetadata map[string]map[string]string `json:"state_metadata,omitempty"`
+		History       *[]Record                    `json:"history,omitempty"`
 	}{
-		Name:        f.Name,
-		Current:     f.GetState(),
-		States:      states,
-		Transitions: trans,
+		Name:          f.Name,
+		Version:       f.version,
+		Current:       f.GetState(),
+		Initial:       f.initial,
+		States:        states,
+		Final:         f.finalNames(),
+		FinalExit:     f.allowFinalExit,
+		Deterministic: f.deterministic,
+		Transitions:   trans,
+		Parents:       f.parents,
+		Timeouts:      timeouts,
+		Entered:       entered,
+		Metadata:      f.meta,
+		StateMetadata: f.stateMeta,
+		History:       history,
 	})
 }
 
 func (f *FSM) UnmarshalJSON(data []byte) error {
 	temp := struct {
-		Name        string       `json:"name"`
-		Current     string       `json:"current"`
-		States      []string     `json:"states"`
-		Transitions []transition `json:"transitions"`
+		Name          string                       `json:"name"`
+		Version       int                          `json:"version"`
+		Current       string                       `json:"current"`
+		Initial       string                       `json:"initial"`
+		States        []string                     `json:"states"`
+		Final         []string                     `json:"final"`
+		FinalExit     bool                         `json:"allow_final_exit"`
+		Deterministic bool                         `json:"deterministic"`
+		Transitions   []transition                 `json:"transitions"`
+		Parents       map[string]string            `json:"parents"`
+		Timeouts      []timeoutJSON                `json:"timeouts"`
+		Entered       map[string]time.Time         `json:"entered"`
+		Metadata      map[string]string            `json:"metadata"`
+		StateMetadata map[string]map[string]string `json:"state_metadata"`
+		History       []Record                     `json:"history"`
 	}{}
 	if err := json.Unmarshal(data, &temp); err != nil {
 		return err
 	}
+	// a versioned fsm keeps its definition and upgrades the data of the other versions
+	if f.version > 0 && temp.Version != f.version {
+		if err := f.upgrade(temp.Version, temp.Current, temp.Entered); err != nil {
+			return err
+		}
+		f.history = temp.History
+		f.recordHistory = temp.History != nil
+		return nil
+	}
 	f.Name = temp.Name
+	f.version = temp.Version
+	f.history = temp.History
+	if temp.History != nil {
+		f.recordHistory = true
+	}
 	f.state = createIntState()
 	f.states = make(map[string]bool)
 	f.adj = make([]transition, 0)
@@ -82,6 +152,36 @@ func (f *FSM) UnmarshalJSON(data []byte) error {
 			f.states[val] = true
 		}
 	}
+	f.final = nil
+	f.allowFinalExit = temp.FinalExit
+	for _, val := range temp.Final {
+		if err := f.AddFinal(val); err != nil {
+			return err
+		}
+	}
+	f.parents = nil
+	for child, parent := range temp.Parents {
+		if ok := f.states[child]; !ok {
+			return ErrStateNotFound
+		}
+		if ok := f.states[parent]; !ok {
+			return ErrStateNotFound
+		}
+		if f.parents == nil {
+			f.parents = make(map[string]string)
+		}
+		f.parents[child] = parent
+	}
+	// a parent chain longer than the number of sub states is a cycle
+	for child := range f.parents {
+		steps := 0
+		for s := child; s != ""; s = f.parents[s] {
+			if steps > len(f.parents) {
+				return ErrInvalidParent
+			}
+			steps++
+		}
+	}
 	for _, trans := range temp.Transitions {
 		if !isValidName(trans.Action) {
 			return ErrInvalidName
@@ -99,12 +199,55 @@ func (f *FSM) UnmarshalJSON(data []byte) error {
 			Action: trans.Action,
 		})
 	}
+	f.deterministic = false
+	if err := f.SetDeterministic(temp.Deterministic); err != nil {
+		return err
+	}
+	f.meta = nil
+	for k, v := range temp.Metadata {
+		f.SetMeta(k, v)
+	}
+	f.stateMeta = nil
+	for state, meta := range temp.StateMetadata {
+		for k, v := range meta {
+			if err := f.SetStateMeta(state, k, v); err != nil {
+				return err
+			}
+		}
+	}
+	f.timeouts = nil
+	for _, t := range temp.Timeouts {
+		after, err := time.ParseDuration(t.After)
+		if err != nil {
+			return ErrInvalidTimeout
+		}
+		if err = f.AddTimeout(t.State, after, t.Action); err != nil {
+			return err
+		}
+	}
 	if len(f.adj) > 0 {
 		f.state.current = ready
 	}
-	err := f.Init(temp.Current)
-	if err != nil {
-		return err
+	// a definition without current state is not initialized
+	f.current = ""
+	f.entered = nil
+	if temp.Current != "" {
+		if err := f.Init(temp.Current); err != nil {
+			return err
+		}
+	}
+	f.initial = ""
+	if temp.Initial != "" {
+		if ok := f.states[temp.Initial]; !ok {
+			return ErrStateNotFound
+		}
+		f.initial = temp.Initial
+	}
+	// pending timers keep the time their state was entered
+	for state, at := range temp.Entered {
+		if _, ok := f.entered[state]; ok {
+			f.entered[state] = at
+		}
 	}
 	return nil
 }
@@ -115,3 +115,20 @@ func TestUnmarshal(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUnmarshalDefinition(t *testing.T) {
+	b := []byte(`{"name":"Customer Plan Status","initial":"TRIAL","states":["TRIAL","BASIC"],"transitions":[{"from":"TRIAL","to":"BASIC","action":"UPGRATE"}]}`)
+	var f FSM
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.GetState() != "" || f.GetInitial() != "TRIAL" {
+		t.Errorf("a definition should not have a current state, got %v", f.GetState())
+	}
+	if err := f.Init(f.GetInitial()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Exec("UPGRATE", "BASIC", nil); err != nil {
+		t.Errorf("It should work, got %v", err)
+	}
+}
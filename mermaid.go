@@ -0,0 +1,180 @@
+package fsm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrInvalidMermaid = errors.New("invalid mermaid diagram")
+
+// Mermaid returns the mermaid stateDiagram-v2 representation of the fsm
+// The name of the fsm is written as the diagram title
+// Sub states are written as composite states
+func (f *FSM) Mermaid() string {
+	builder := strings.Builder{}
+	if f.Name != "" {
+		builder.WriteString(fmt.Sprintf("---\ntitle: %v\n---\n", f.Name))
+	}
+	builder.WriteString("stateDiagram-v2\n")
+
+	f.writeMermaidStates(&builder, f.children(), "", "    ")
+
+	if f.initial != "" {
+		builder.WriteString(fmt.Sprintf("    [*] --> %v\n", f.initial))
+	}
+	for _, adj := range f.adj {
+		builder.WriteString(fmt.Sprintf("    %v --> %v : %v\n", adj.From, adj.To, adj.Action))
+	}
+	for _, name := range f.finalNames() {
+		builder.WriteString(fmt.Sprintf("    %v --> [*]\n", name))
+	}
+	return builder.String()
+}
+
+// writeMermaidStates declares every state, so the ones without transitions are not lost
+func (f *FSM) writeMermaidStates(builder *strings.Builder, children map[string][]string, parent string, indent string) {
+	for _, name := range children[parent] {
+		if len(children[name]) == 0 {
+			builder.WriteString(fmt.Sprintf("%vstate %v\n", indent, name))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%vstate %v {\n", indent, name))
+		f.writeMermaidStates(builder, children, name, indent+"    ")
+		builder.WriteString(indent + "}\n")
+	}
+}
+
+// ParseMermaid creates a fsm from a mermaid stateDiagram-v2 diagram
+// It supports state declarations, composite states, transitions labelled with their action,
+// the initial state ([*] --> STATE), final states (STATE --> [*]), comments and the title
+// State and action names must be valid names, transitions must have an action
+// Errors report the line of the diagram
+func ParseMermaid(r io.Reader) (*FSM, error) {
+	f := NewFSM("")
+	scanner := bufio.NewScanner(r)
+	line := 0
+	header := false
+	frontMatter := false
+	var parents []string
+	var initial string
+
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: line %v: %v", ErrInvalidMermaid, line, fmt.Sprintf(format, args...))
+	}
+	declare := func(name string) error {
+		if ok := f.states[name]; ok {
+			return nil
+		}
+		var err error
+		if len(parents) > 0 {
+			err = f.AddSubState(parents[len(parents)-1], name)
+		} else {
+			err = f.AddState(name)
+		}
+		if err != nil {
+			return fail("state %v: %v", name, err)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "%%") {
+			continue
+		}
+		if !header {
+			switch {
+			case text == "---":
+				frontMatter = !frontMatter
+			case frontMatter && strings.HasPrefix(text, "title:"):
+				f.Name = strings.TrimSpace(strings.TrimPrefix(text, "title:"))
+			case frontMatter:
+			case text == "stateDiagram-v2":
+				header = true
+			default:
+				return nil, fail("expected stateDiagram-v2, got %v", text)
+			}
+			continue
+		}
+
+		switch {
+		case text == "}":
+			if len(parents) == 0 {
+				return nil, fail("unexpected }")
+			}
+			parents = parents[:len(parents)-1]
+		case strings.HasPrefix(text, "state "):
+			decl := strings.TrimSpace(strings.TrimPrefix(text, "state "))
+			composite := strings.HasSuffix(decl, "{")
+			name := strings.TrimSpace(strings.TrimSuffix(decl, "{"))
+			if err := declare(name); err != nil {
+				return nil, err
+			}
+			if composite {
+				parents = append(parents, name)
+			}
+		case strings.Contains(text, "-->"):
+			parts := strings.SplitN(text, "-->", 2)
+			src := strings.TrimSpace(parts[0])
+			des := strings.TrimSpace(parts[1])
+			action := ""
+			if i := strings.Index(des, ":"); i >= 0 {
+				action = strings.TrimSpace(des[i+1:])
+				des = strings.TrimSpace(des[:i])
+			}
+			switch {
+			case src == "[*]" && des == "[*]":
+				return nil, fail("transition from [*] to [*]")
+			case src == "[*]":
+				if len(parents) > 0 {
+					return nil, fail("initial state of composite state %v is not supported", parents[len(parents)-1])
+				}
+				if err := declare(des); err != nil {
+					return nil, err
+				}
+				initial = des
+			case des == "[*]":
+				if err := declare(src); err != nil {
+					return nil, err
+				}
+				if err := f.AddFinal(src); err != nil {
+					return nil, fail("%v", err)
+				}
+			default:
+				if action == "" {
+					return nil, fail("transition %v --> %v without action", src, des)
+				}
+				if err := declare(src); err != nil {
+					return nil, err
+				}
+				if err := declare(des); err != nil {
+					return nil, err
+				}
+				if err := f.AddTrans(src, des, action); err != nil {
+					return nil, fail("transition %v --> %v : %v: %v", src, des, action, err)
+				}
+			}
+		default:
+			return nil, fail("unsupported statement %v", text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		return nil, fmt.Errorf("%w: missing stateDiagram-v2", ErrInvalidMermaid)
+	}
+	if len(parents) > 0 {
+		return nil, fmt.Errorf("%w: state %v is not closed", ErrInvalidMermaid, parents[len(parents)-1])
+	}
+	if initial != "" {
+		if err := f.Init(initial); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
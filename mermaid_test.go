@@ -0,0 +1,111 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func assertSameJSON(t *testing.T, a *FSM, b *FSM) {
+	t.Helper()
+	ja, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ja) != string(jb) {
+		t.Errorf("fsm differ:\n%v\n%v", string(ja), string(jb))
+	}
+}
+
+func TestMermaidRoundTrip(t *testing.T) {
+	plan1, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan2, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plan2.State.AddFinal("GOLD"); err != nil {
+		t.Fatal(err)
+	}
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []*FSM{plan1.State, plan2.State, tenant.State} {
+		parsed, err := ParseMermaid(strings.NewReader(f.Mermaid()))
+		if err != nil {
+			t.Fatalf("%v: %v\n%v", f.Name, err, f.Mermaid())
+		}
+		assertSameJSON(t, f, parsed)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	a, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `---
+title: SAAS Account State V1.0
+---
+stateDiagram-v2
+    state BASIC
+    state PREMIUM
+    state TRIAL
+    [*] --> TRIAL
+    TRIAL --> BASIC : UPGRATE
+    TRIAL --> PREMIUM : UPGRATE
+    BASIC --> PREMIUM : UPGRATE
+    PREMIUM --> BASIC : DOWNGRATE
+`
+	if got := a.State.Mermaid(); got != expected {
+		t.Errorf("wrong mermaid:\n%v", got)
+	}
+}
+
+func TestParseMermaid(t *testing.T) {
+	diagram := `%% sketched by product
+stateDiagram-v2
+    [*] --> QUEUED
+    QUEUED --> RUNNING : START
+    RUNNING --> DONE : FINISH
+    RUNNING --> FAILED : FAIL
+    DONE --> [*]
+    FAILED --> [*]
+`
+	f, err := ParseMermaid(strings.NewReader(diagram))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.GetState() != "QUEUED" || !f.IsFinal("DONE") || !f.IsFinal("FAILED") {
+		t.Errorf("wrong fsm: %v", f.GetTrans())
+	}
+	if err = f.Fire("START", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []struct {
+		diagram string
+		line    string
+	}{
+		{"flowchart LR\n", "line 1"},
+		{"stateDiagram-v2\n    A --> B\n", "line 2"},
+		{"stateDiagram-v2\n    A --> B : GO\n    a --> B : GO\n", "line 3"},
+		{"stateDiagram-v2\n    A --> B : go\n", "line 2"},
+		{"stateDiagram-v2\n    state A {\n", "not closed"},
+		{"stateDiagram-v2\n    note right of A : text\n", "line 2"},
+	}
+	for _, test := range invalid {
+		_, err = ParseMermaid(strings.NewReader(test.diagram))
+		if !errors.Is(err, ErrInvalidMermaid) || !strings.Contains(err.Error(), test.line) {
+			t.Errorf("should errored invalid mermaid at %v, got %v", test.line, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package fsm
+
+// SetMeta sets a metadata entry of the fsm
+func (f *FSM) SetMeta(key string, value string) {
+	if f.meta == nil {
+		f.meta = make(map[string]string)
+	}
+	f.meta[key] = value
+}
+
+// GetMeta returns the metadata of the fsm
+func (f *FSM) GetMeta() map[string]string {
+	return copyMeta(f.meta)
+}
+
+// SetStateMeta sets a metadata entry of the given state
+// It validates state exists prior to set it
+func (f *FSM) SetStateMeta(state string, key string, value string) error {
+	if ok := f.states[state]; !ok {
+		return ErrStateNotFound
+	}
+	if f.stateMeta == nil {
+		f.stateMeta = make(map[string]map[string]string)
+	}
+	if f.stateMeta[state] == nil {
+		f.stateMeta[state] = make(map[string]string)
+	}
+	f.stateMeta[state][key] = value
+	return nil
+}
+
+// GetStateMeta returns the metadata of the given state
+func (f *FSM) GetStateMeta(state string) map[string]string {
+	return copyMeta(f.stateMeta[state])
+}
+
+func copyMeta(meta map[string]string) map[string]string {
+	if meta == nil {
+		return nil
+	}
+	c := make(map[string]string, len(meta))
+	for k, v := range meta {
+		c[k] = v
+	}
+	return c
+}
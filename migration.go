@@ -0,0 +1,89 @@
+package fsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Migration upgrades the instances of a definition from a version to a later one
+type Migration struct {
+	From int
+	To   int
+	// States maps the renamed, merged and removed states to the state replacing them
+	States map[string]string
+}
+
+// SetVersion sets the version of the fsm definition, it is recorded in the JSON representation
+func (f *FSM) SetVersion(version int) {
+	f.version = version
+}
+
+// GetVersion gets the version of the fsm definition
+func (f *FSM) GetVersion() int {
+	return f.version
+}
+
+// AddMigration declares how to upgrade a fsm saved with an older version
+// When a fsm with a version unmarshals the JSON of an older version, it keeps its definition
+// and only takes the current state, entered times and history, upgraded by the migrations
+// It validates the versions and the state names prior to add it
+func (f *FSM) AddMigration(m Migration) error {
+	if m.From < 0 || m.To <= m.From {
+		return fmt.Errorf("%w: from %v to %v", ErrInvalidMigration, m.From, m.To)
+	}
+	if _, ok := f.migrations[m.From]; ok {
+		return fmt.Errorf("%w: from %v already exists", ErrInvalidMigration, m.From)
+	}
+	states := make(map[string]string, len(m.States))
+	for old, s := range m.States {
+		if !isValidName(old) || !isValidName(s) {
+			return ErrInvalidName
+		}
+		states[old] = s
+	}
+	m.States = states
+	if f.migrations == nil {
+		f.migrations = make(map[int]Migration)
+	}
+	f.migrations[m.From] = m
+	return nil
+}
+
+// migrateState returns the state replacing the given state saved with the given version
+func (f *FSM) migrateState(version int, state string) (string, error) {
+	if version > f.version {
+		return "", fmt.Errorf("%w: %v is newer than %v", ErrInvalidVersion, version, f.version)
+	}
+	for v := version; v < f.version; {
+		m, ok := f.migrations[v]
+		if !ok || m.To > f.version {
+			return "", fmt.Errorf("%w: no migration from %v to %v", ErrInvalidVersion, v, f.version)
+		}
+		if s, ok := m.States[state]; ok {
+			state = s
+		}
+		v = m.To
+	}
+	if ok := f.states[state]; !ok {
+		return "", ErrStateNotFound
+	}
+	return state, nil
+}
+
+// upgrade set the current state saved with the given version, the entered times are kept for the states still active
+func (f *FSM) upgrade(version int, current string, entered map[string]time.Time) error {
+	state, err := f.migrateState(version, current)
+	if err != nil {
+		return err
+	}
+	f.reset(state)
+	for s, at := range entered {
+		if s, err = f.migrateState(version, s); err != nil {
+			continue
+		}
+		if _, ok := f.entered[s]; ok {
+			f.entered[s] = at
+		}
+	}
+	return nil
+}
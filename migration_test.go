@@ -0,0 +1,109 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMigration(t *testing.T) {
+	v1, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1.State.SetVersion(1)
+	if err = v1.State.Exec("UPGRATE", "PREMIUM", nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(v1.State)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// v2 moves the PREMIUM accounts to GOLD, v3 drops GOLD and moves them back
+	migrations := []Migration{
+		{From: 1, To: 2, States: map[string]string{"PREMIUM": "GOLD"}},
+		{From: 2, To: 3, States: map[string]string{"GOLD": "PREMIUM"}},
+	}
+	v2, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2.State.SetVersion(2)
+	if err = v2.State.AddMigration(migrations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, v2.State); err != nil {
+		t.Fatal(err)
+	}
+	if v2.State.GetState() != "GOLD" || v2.State.GetVersion() != 2 {
+		t.Errorf("state should be GOLD at version 2, got %v at %v", v2.State.GetState(), v2.State.GetVersion())
+	}
+	if _, ok := v2.State.findTrans("GOLD", "PREMIUM", "DOWNGRATE"); !ok {
+		t.Errorf("the v2 definition should be kept")
+	}
+
+	v3, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v3.State.SetVersion(3)
+	for _, m := range migrations {
+		if err = v3.State.AddMigration(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = json.Unmarshal(data, v3.State); err != nil {
+		t.Fatal(err)
+	}
+	if v3.State.GetState() != "PREMIUM" {
+		t.Errorf("state should be PREMIUM, got %v", v3.State.GetState())
+	}
+
+	// the definition migrates its instances too
+	d := NewDefinition(v3.State)
+	i := d.NewInstance()
+	if err = json.Unmarshal([]byte(`{"version":2,"current":"GOLD"}`), i); err != nil {
+		t.Fatal(err)
+	}
+	if i.GetState() != "PREMIUM" {
+		t.Errorf("state should be PREMIUM, got %v", i.GetState())
+	}
+	if data, err = json.Marshal(i); err != nil || string(data) != `{"version":3,"current":"PREMIUM"}` {
+		t.Errorf("wrong instance JSON: %s %v", data, err)
+	}
+}
+
+func TestMigrationErrors(t *testing.T) {
+	a, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := a.State
+	f.SetVersion(3)
+	if err = f.AddMigration(Migration{From: 2, To: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{
+		`{"version":4,"current":"TRIAL"}`,
+		`{"version":1,"current":"TRIAL"}`,
+	} {
+		if err = json.Unmarshal([]byte(data), f); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("should errored invalid version, got %v", err)
+		}
+	}
+	if err = json.Unmarshal([]byte(`{"version":2,"current":"GOLD"}`), f); err != ErrStateNotFound {
+		t.Errorf("should errored state not found, got %v", err)
+	}
+	for _, m := range []Migration{
+		{From: 3, To: 3},
+		{From: 2, To: 3},
+	} {
+		if err = f.AddMigration(m); !errors.Is(err, ErrInvalidMigration) {
+			t.Errorf("should errored invalid migration, got %v", err)
+		}
+	}
+	if err = f.AddMigration(Migration{From: 3, To: 4, States: map[string]string{"basic": "BASIC"}}); err != ErrInvalidName {
+		t.Errorf("should errored invalid name, got %v", err)
+	}
+}
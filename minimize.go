@@ -0,0 +1,204 @@
+package fsm
+
+import (
+	"sort"
+)
+
+// Minimize returns an equivalent deterministic fsm with the fewest states using Hopcroft's algorithm,
+// and maps every state of the fsm to the state it was merged into
+// Two states are merged when they are both final or both not, and every action leads them to merged states
+// The merged state is named after its smallest member
+// When the fsm has an initial state, the states reachable neither from it nor from the current state are dropped
+// and are not mapped
+// Sub states are flattened like in Determinize, the guards, hooks and timeouts are not kept
+// It fails with ErrNondeterministic when the fsm is not deterministic
+func Minimize(f *FSM) (*FSM, map[string]string, error) {
+	if err := f.checkDeterministic(); err != nil {
+		return nil, nil, err
+	}
+	actions := f.Actions()
+
+	states := f.stateNames()
+	if f.initial != "" {
+		reachable := make(map[string]bool)
+		queue := []string{f.initial, f.current}
+		for len(queue) > 0 {
+			s := queue[0]
+			queue = queue[1:]
+			if s == "" || reachable[s] {
+				continue
+			}
+			reachable[s] = true
+			for _, action := range actions {
+				queue = append(queue, f.targets(s, action)...)
+			}
+		}
+		states = states[:0]
+		for _, s := range f.stateNames() {
+			if reachable[s] {
+				states = append(states, s)
+			}
+		}
+	}
+
+	// the missing transitions lead to an extra dead state, the last one
+	index := make(map[string]int, len(states))
+	for i, s := range states {
+		index[s] = i
+	}
+	dead := len(states)
+	delta := make([][]int, dead+1)
+	for i := range delta {
+		delta[i] = make([]int, len(actions))
+		for a, action := range actions {
+			delta[i][a] = dead
+			if i == dead {
+				continue
+			}
+			if targets := f.targets(states[i], action); len(targets) > 0 {
+				delta[i][a] = index[targets[0]]
+			}
+		}
+	}
+	inverse := make([][][]int, len(actions))
+	for a := range actions {
+		inverse[a] = make([][]int, dead+1)
+		for i := range delta {
+			inverse[a][delta[i][a]] = append(inverse[a][delta[i][a]], i)
+		}
+	}
+
+	// the initial partition separates the final states, the other states and the dead state
+	var blocks [][]int
+	blockOf := make([]int, dead+1)
+	var final, other []int
+	for i, s := range states {
+		if f.final[s] {
+			final = append(final, i)
+		} else {
+			other = append(other, i)
+		}
+	}
+	waiting := make(map[int]bool)
+	for _, b := range [][]int{final, other, {dead}} {
+		if len(b) == 0 {
+			continue
+		}
+		for _, i := range b {
+			blockOf[i] = len(blocks)
+		}
+		waiting[len(blocks)] = true
+		blocks = append(blocks, b)
+	}
+
+	for len(waiting) > 0 {
+		var splitter int
+		for b := range waiting {
+			splitter = b
+			break
+		}
+		delete(waiting, splitter)
+		members := append([]int{}, blocks[splitter]...)
+		for a := range actions {
+			// the states led into the splitter by the action, grouped by block
+			in := make(map[int][]int)
+			for _, j := range members {
+				for _, i := range inverse[a][j] {
+					in[blockOf[i]] = append(in[blockOf[i]], i)
+				}
+			}
+			var touched []int
+			for b := range in {
+				touched = append(touched, b)
+			}
+			sort.Ints(touched)
+			for _, b := range touched {
+				x := in[b]
+				if len(x) == len(blocks[b]) {
+					continue
+				}
+				inX := make(map[int]bool, len(x))
+				for _, i := range x {
+					inX[i] = true
+				}
+				var rest []int
+				for _, i := range blocks[b] {
+					if !inX[i] {
+						rest = append(rest, i)
+					}
+				}
+				sort.Ints(x)
+				blocks[b] = x
+				n := len(blocks)
+				blocks = append(blocks, rest)
+				for _, i := range rest {
+					blockOf[i] = n
+				}
+				if waiting[b] || len(rest) <= len(x) {
+					waiting[n] = true
+				} else {
+					waiting[b] = true
+				}
+			}
+		}
+	}
+
+	// the states are sorted, the smallest index of a block is its smallest name
+	merged := make(map[string]string, len(states))
+	for i, s := range states {
+		merged[s] = states[minMember(blocks[blockOf[i]])]
+	}
+
+	m := NewFSM(f.Name)
+	m.meta = copyMeta(f.meta)
+	for _, s := range states {
+		if merged[s] != s {
+			continue
+		}
+		if err := m.AddState(s); err != nil {
+			return nil, nil, err
+		}
+		if f.final[s] {
+			if err := m.AddFinal(s); err != nil {
+				return nil, nil, err
+			}
+		}
+		for k, v := range f.stateMeta[s] {
+			if err := m.SetStateMeta(s, k, v); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for i, s := range states {
+		if merged[s] != s {
+			continue
+		}
+		for a, action := range actions {
+			if des := delta[i][a]; des != dead {
+				if err := m.AddTrans(s, merged[states[des]], action); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	m.deterministic = true
+	if f.initial != "" {
+		if err := m.Init(merged[f.initial]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if f.current != "" {
+		m.reset(merged[f.current])
+	}
+	return m, merged, nil
+}
+
+func minMember(block []int) int {
+	min := block[0]
+	for _, i := range block[1:] {
+		if i < min {
+			min = i
+		}
+	}
+	return min
+}
@@ -0,0 +1,114 @@
+package fsm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	// PAID_CARD and PAID_CASH behave the same, so do SHIPPED and DELIVERED
+	f, err := New("Order", [][3]string{
+		{"NEW", "PAID_CARD", "CARD"},
+		{"NEW", "PAID_CASH", "CASH"},
+		{"PAID_CARD", "SHIPPED", "SHIP"},
+		{"PAID_CASH", "SHIPPED", "SHIP"},
+		{"PAID_CARD", "CANCELED", "CANCEL"},
+		{"PAID_CASH", "CANCELED", "CANCEL"},
+		{"SHIPPED", "DELIVERED", "DELIVER"},
+		{"DELIVERED", "DELIVERED", "DELIVER"},
+		{"LOST", "NEW", "FIND"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"SHIPPED", "DELIVERED"} {
+		if err = f.AddFinal(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = f.Init("NEW"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Fire("CASH", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	m, merged, err := Minimize(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMerged := map[string]string{
+		"NEW":       "NEW",
+		"PAID_CARD": "PAID_CARD",
+		"PAID_CASH": "PAID_CARD",
+		"SHIPPED":   "DELIVERED",
+		"DELIVERED": "DELIVERED",
+		"CANCELED":  "CANCELED",
+	}
+	if !reflect.DeepEqual(merged, wantMerged) {
+		t.Errorf("wrong merged states: %v", merged)
+	}
+	wantStates := []string{"CANCELED", "DELIVERED", "NEW", "PAID_CARD"}
+	if got := m.stateNames(); !reflect.DeepEqual(got, wantStates) {
+		t.Errorf("wrong states: %v", got)
+	}
+	if m.GetInitial() != "NEW" || m.GetState() != "PAID_CARD" {
+		t.Errorf("wrong initial or current state: %v %v", m.GetInitial(), m.GetState())
+	}
+	if !m.IsFinal("DELIVERED") || m.IsFinal("CANCELED") {
+		t.Errorf("wrong final states: %v", m.finalNames())
+	}
+	for _, tr := range [][3]string{
+		{"NEW", "PAID_CARD", "CARD"},
+		{"NEW", "PAID_CARD", "CASH"},
+		{"PAID_CARD", "DELIVERED", "SHIP"},
+		{"DELIVERED", "DELIVERED", "DELIVER"},
+	} {
+		if _, ok := m.findTrans(tr[0], tr[1], tr[2]); !ok {
+			t.Errorf("transition %v should exist", tr)
+		}
+	}
+	if len(m.adj) != 5 {
+		t.Errorf("wrong transitions:\n%v", m.GetTrans())
+	}
+}
+
+func TestMinimizeNoInitial(t *testing.T) {
+	// DONE and FAILED have no transitions but only DONE is final
+	f, err := New("Job", [][3]string{
+		{"QUEUED", "DONE", "FINISH"},
+		{"QUEUED", "FAILED", "FAIL"},
+		{"RETRIED", "DONE", "FINISH"},
+		{"RETRIED", "FAILED", "FAIL"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddFinal("DONE"); err != nil {
+		t.Fatal(err)
+	}
+	_, merged, err := Minimize(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"QUEUED":  "QUEUED",
+		"RETRIED": "QUEUED",
+		"DONE":    "DONE",
+		"FAILED":  "FAILED",
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("wrong merged states: %v", merged)
+	}
+}
+
+func TestMinimizeNondeterministic(t *testing.T) {
+	f, err := NewPlan("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Minimize(f.State); !errors.Is(err, ErrNondeterministic) {
+		t.Errorf("should errored nondeterministic transition, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package fsm
 
 import (
 	"testing"
+	"time"
 )
 
 type plan struct {
@@ -120,6 +121,140 @@ func NewPlan2(planName string) (*plan, error) {
 	return nil, err
 }
 
+func NewTenant(tenantstatus string) (*plan, error) {
+
+	f := NewFSM("Tenant")
+
+	err := f.AddState("ACTIVE")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddState("SUSPENDED")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range []string{"TRIAL", "BASIC", "PREMIUM"} {
+		err = f.AddSubState("ACTIVE", s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = f.AddSubState("PREMIUM", "GOLD")
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.AddTrans("ACTIVE", "SUSPENDED", "SUSPEND")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddTrans("SUSPENDED", "BASIC", "RESUME")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddTrans("TRIAL", "BASIC", "UPGRADE")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddTrans("BASIC", "PREMIUM", "UPGRADE")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddTrans("PREMIUM", "GOLD", "UPGRADE")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &plan{
+		Id:    0,
+		Name:  "Tenant",
+		State: f,
+	}
+	err = f.Init(tenantstatus)
+	if err == nil {
+		return c, nil
+	}
+	return nil, err
+}
+
+func NewTenantRegions(billingstatus string, provisioningstatus string) (*Parallel, error) {
+
+	billing, err := New("Billing", [][3]string{
+		{"TRIAL", "PAID", "PAY"},
+		{"PAID", "OVERDUE", "EXPIRE"},
+		{"OVERDUE", "CANCELED", "CANCEL"},
+		{"TRIAL", "CANCELED", "CANCEL"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	provisioning, err := New("Provisioning", [][3]string{
+		{"PENDING", "READY", "PROVISION"},
+		{"READY", "DELETED", "CANCEL"},
+		{"PENDING", "DELETED", "CANCEL"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewParallel("Tenant")
+	err = p.AddRegion("BILLING", billing)
+	if err != nil {
+		return nil, err
+	}
+	err = p.AddRegion("PROVISIONING", provisioning)
+	if err != nil {
+		return nil, err
+	}
+	err = p.Init(map[string]string{"BILLING": billingstatus, "PROVISIONING": provisioningstatus})
+	if err == nil {
+		return p, nil
+	}
+	return nil, err
+}
+
+func NewTrial(clock Clock) (*plan, error) {
+
+	c, err := NewPlan("TRIAL")
+	if err != nil {
+		return nil, err
+	}
+	f := c.State
+	f.SetClock(clock)
+
+	err = f.AddState("EXPIRED")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddState("DELETED")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddTrans("TRIAL", "EXPIRED", "EXPIRE")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddTrans("EXPIRED", "DELETED", "DELETE")
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.AddTimeout("TRIAL", 14*24*time.Hour, "EXPIRE")
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddTimeout("EXPIRED", 30*24*time.Hour, "DELETE")
+	if err != nil {
+		return nil, err
+	}
+	// enter TRIAL again so the timers count from the given clock
+	err = f.Init("TRIAL")
+	if err == nil {
+		return c, nil
+	}
+	return nil, err
+}
+
 func (a *plan) stateTransitionHandler(pre string, cur string, action string) {
 	a.t.Logf("Previous State:%v, New State:%v, Action:%v", pre, cur, action)
 }
@@ -0,0 +1,186 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+var ErrRegionNotFound = errors.New("region not found")
+var ErrRegionAlExists = errors.New("region already exists")
+
+// Parallel is a machine made of several fsm, its regions, that are active at the same time.
+// An action advances every region that has a transition for it from its current state.
+type Parallel struct {
+	Name    string
+	regions []region
+}
+
+type region struct {
+	name string
+	fsm  *FSM
+}
+
+// NewParallel creates a pointer to a brand new Parallel machine without regions
+func NewParallel(name string) *Parallel {
+	return &Parallel{Name: name}
+}
+
+// AddRegion adds a new region into the machine
+// It validates region name prior to add it
+// It validates region is unique
+func (p *Parallel) AddRegion(name string, f *FSM) error {
+	if !isValidName(name) {
+		return ErrInvalidName
+	}
+	if p.Region(name) != nil {
+		return ErrRegionAlExists
+	}
+	p.regions = append(p.regions, region{name: name, fsm: f})
+	return nil
+}
+
+// Region returns the fsm of the given region, nil if it does not exist
+func (p *Parallel) Region(name string) *FSM {
+	for _, r := range p.regions {
+		if r.name == name {
+			return r.fsm
+		}
+	}
+	return nil
+}
+
+// Terminated reports whether every region is in a final state
+func (p *Parallel) Terminated() bool {
+	for _, r := range p.regions {
+		if !r.fsm.Terminated() {
+			return false
+		}
+	}
+	return len(p.regions) > 0
+}
+
+// GetState gets the current state of every region
+func (p *Parallel) GetState() map[string]string {
+	states := make(map[string]string, len(p.regions))
+	for _, r := range p.regions {
+		states[r.name] = r.fsm.GetState()
+	}
+	return states
+}
+
+// Init set the current state of the given regions
+// It validates every region and state exists prior to set any of them
+func (p *Parallel) Init(states map[string]string) error {
+	for name, state := range states {
+		f := p.Region(name)
+		if f == nil {
+			return ErrRegionNotFound
+		}
+		if ok := f.states[state]; !ok {
+			return ErrStateNotFound
+		}
+	}
+	for name, state := range states {
+		if err := p.Region(name).Init(state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fire executes the given action in every region that has a transition for it,
+// a region in a final state is left out like a region without transition
+// Every region is checked, guards included, before any of them moves:
+// either all the involved regions move or none does
+// It fails with ErrExecNotAllowed when no region has a transition for the action
+// The callback, if any, is called once after all the regions moved
+func (p *Parallel) Fire(action string, callback func(previous map[string]string, new map[string]string, action string)) error {
+	return p.FireContext(context.Background(), action, callback)
+}
+
+// FireContext is like Fire but the hooks of the regions receive the context
+func (p *Parallel) FireContext(ctx context.Context, action string, callback func(previous map[string]string, new map[string]string, action string)) error {
+	type step struct {
+		fsm *FSM
+		des string
+	}
+	var steps []step
+	for _, r := range p.regions {
+		des, err := r.fsm.resolve(action)
+		if err == ErrExecNotAllowed || err == ErrFinalState {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err = r.fsm.check(ctx, action, des); err != nil {
+			return err
+		}
+		steps = append(steps, step{fsm: r.fsm, des: des})
+	}
+	if len(steps) == 0 {
+		return ErrExecNotAllowed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	previous := p.GetState()
+	for _, s := range steps {
+		s.fsm.move(ctx, s.fsm.now(), action, s.des, nil)
+	}
+	if callback != nil {
+		callback(previous, p.GetState(), action)
+	}
+	return nil
+}
+
+type regionJSON struct {
+	Name string `json:"name"`
+	FSM  *FSM   `json:"fsm"`
+}
+
+func (p *Parallel) MarshalJSON() ([]byte, error) {
+	regions := make([]regionJSON, 0, len(p.regions))
+	for _, r := range p.regions {
+		regions = append(regions, regionJSON{Name: r.name, FSM: r.fsm})
+	}
+	return json.Marshal(&struct {
+		Name    string       `json:"name"`
+		Regions []regionJSON `json:"regions"`
+	}{
+		Name:    p.Name,
+		Regions: regions,
+	})
+}
+
+// UnmarshalJSON restores the machine, regions that already exist
+// are restored in place so their guards and hooks are kept
+func (p *Parallel) UnmarshalJSON(data []byte) error {
+	temp := struct {
+		Name    string `json:"name"`
+		Regions []struct {
+			Name string          `json:"name"`
+			FSM  json.RawMessage `json:"fsm"`
+		} `json:"regions"`
+	}{}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+	restored := &Parallel{Name: temp.Name}
+	for _, r := range temp.Regions {
+		f := p.Region(r.Name)
+		if f == nil {
+			f = &FSM{}
+		}
+		if err := json.Unmarshal(r.FSM, f); err != nil {
+			return err
+		}
+		if err := restored.AddRegion(r.Name, f); err != nil {
+			return err
+		}
+	}
+	*p = *restored
+	return nil
+}
@@ -0,0 +1,90 @@
+package fsm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	p, err := NewTenantRegions("TRIAL", "PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.AddRegion("BILLING", NewFSM("Dup")); err != ErrRegionAlExists {
+		t.Errorf("should errored region already exists, got %v", err)
+	}
+	if err := p.Init(map[string]string{"SHIPPING": "SENT"}); err != ErrRegionNotFound {
+		t.Errorf("should errored region not found, got %v", err)
+	}
+
+	err = p.Fire("PROVISION", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := p.GetState()
+	if state["BILLING"] != "TRIAL" || state["PROVISIONING"] != "READY" {
+		t.Errorf("only provisioning should move: %v", state)
+	}
+
+	var previous, current map[string]string
+	err = p.Fire("CANCEL", func(pre map[string]string, cur map[string]string, action string) {
+		previous = pre
+		current = cur
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous["BILLING"] != "TRIAL" || current["BILLING"] != "CANCELED" || current["PROVISIONING"] != "DELETED" {
+		t.Errorf("both regions should move: %v -> %v", previous, current)
+	}
+
+	if err = p.Fire("PAY", nil); err != ErrExecNotAllowed {
+		t.Errorf("should errored exec not allowed, got %v", err)
+	}
+}
+
+func TestParallelAllOrNothing(t *testing.T) {
+	p, err := NewTenantRegions("TRIAL", "PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Region("PROVISIONING").AddGuard("PENDING", "DELETED", "CANCEL", "NO_DATA", func(pre string, cur string, action string) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Fire("CANCEL", nil); err == nil {
+		t.Errorf("should errored guard rejected")
+	}
+	state := p.GetState()
+	if state["BILLING"] != "TRIAL" || state["PROVISIONING"] != "PENDING" {
+		t.Errorf("no region should move: %v", state)
+	}
+}
+
+func TestParallelMarshalUnmarshal(t *testing.T) {
+	p, err := NewTenantRegions("TRIAL", "PENDING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Fire("PAY", nil); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p2 Parallel
+	if err = json.Unmarshal(b, &p2); err != nil {
+		t.Fatal(err)
+	}
+	state := p2.GetState()
+	if p2.Name != "Tenant" || state["BILLING"] != "PAID" || state["PROVISIONING"] != "PENDING" {
+		t.Errorf("wrong machine after unmarshal: %v %v", p2.Name, state)
+	}
+	if err = p2.Fire("PROVISION", nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,97 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlantUMLOptions controls how PlantUML renders a fsm, the zero value renders with defaults
+type PlantUMLOptions struct {
+	// Notes are written next to their state, a note can span several lines
+	Notes map[string]string
+	// MetadataNotes writes the metadata of the states without a note as their note
+	MetadataNotes bool
+	// CurrentColor is the color of the current state, LightBlue by default
+	CurrentColor string
+	// NoCurrent disables the highlight of the current state
+	NoCurrent bool
+}
+
+// PlantUML returns the PlantUML state diagram of the fsm
+// The initial state has an arrow from [*], final states have an arrow to [*]
+// and transitions are labelled by their action
+// Sub states are written as composite states
+func (f *FSM) PlantUML(opts PlantUMLOptions) string {
+	if opts.CurrentColor == "" {
+		opts.CurrentColor = "LightBlue"
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("@startuml\n")
+	if f.Name != "" {
+		builder.WriteString(fmt.Sprintf("title %v\n", f.Name))
+	}
+	f.writePlantUMLStates(&builder, opts, f.children(), "", "")
+
+	if f.initial != "" {
+		builder.WriteString(fmt.Sprintf("[*] --> %v\n", f.initial))
+	}
+	for _, adj := range f.adj {
+		builder.WriteString(fmt.Sprintf("%v --> %v : %v\n", adj.From, adj.To, adj.Action))
+	}
+	for _, name := range f.finalNames() {
+		builder.WriteString(fmt.Sprintf("%v --> [*]\n", name))
+	}
+
+	for _, name := range f.stateNames() {
+		note, ok := opts.Notes[name]
+		if !ok && opts.MetadataNotes {
+			note, ok = metadataNote(f.stateMeta[name])
+		}
+		if !ok {
+			continue
+		}
+		lines := strings.Split(strings.TrimRight(note, "\n"), "\n")
+		if len(lines) == 1 {
+			builder.WriteString(fmt.Sprintf("note right of %v : %v\n", name, lines[0]))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("note right of %v\n", name))
+		for _, l := range lines {
+			builder.WriteString(fmt.Sprintf("  %v\n", l))
+		}
+		builder.WriteString("end note\n")
+	}
+	builder.WriteString("@enduml\n")
+	return builder.String()
+}
+
+func (f *FSM) writePlantUMLStates(builder *strings.Builder, opts PlantUMLOptions, children map[string][]string, parent string, indent string) {
+	for _, name := range children[parent] {
+		decl := fmt.Sprintf("%vstate %v", indent, name)
+		if !opts.NoCurrent && name == f.current {
+			decl += " #" + opts.CurrentColor
+		}
+		if len(children[name]) == 0 {
+			builder.WriteString(decl + "\n")
+			continue
+		}
+		builder.WriteString(decl + " {\n")
+		f.writePlantUMLStates(builder, opts, children, name, indent+"  ")
+		builder.WriteString(indent + "}\n")
+	}
+}
+
+// metadataNote writes the metadata entries one per line, sorted by key
+func metadataNote(meta map[string]string) (string, bool) {
+	if len(meta) == 0 {
+		return "", false
+	}
+	lines := make([]string, 0, len(meta))
+	for k, v := range meta {
+		lines = append(lines, fmt.Sprintf("%v: %v", k, v))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), true
+}
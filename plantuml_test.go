@@ -0,0 +1,123 @@
+package fsm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPlantUML(t *testing.T) {
+	f, err := New("Job", [][3]string{
+		{"QUEUED", "RUNNING", "START"},
+		{"RUNNING", "DONE", "FINISH"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddFinal("DONE"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Init("QUEUED"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `@startuml
+title Job
+state DONE
+state QUEUED #LightBlue
+state RUNNING
+[*] --> QUEUED
+QUEUED --> RUNNING : START
+RUNNING --> DONE : FINISH
+DONE --> [*]
+note right of QUEUED : waits for a worker
+note right of RUNNING
+  owner: scheduler
+  retries: 3
+end note
+@enduml
+`
+	got := f.PlantUML(PlantUMLOptions{
+		Notes: map[string]string{
+			"QUEUED":  "waits for a worker",
+			"RUNNING": "owner: scheduler\nretries: 3\n",
+			"MISSING": "ignored",
+		},
+	})
+	if got != expected {
+		t.Errorf("wrong PlantUML:\n%v", got)
+	}
+}
+
+func TestPlantUMLSubStates(t *testing.T) {
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tenant.State
+	got := f.PlantUML(PlantUMLOptions{NoCurrent: true})
+	expected := `state ACTIVE {
+  state BASIC
+  state PREMIUM {
+    state GOLD
+  }
+  state TRIAL
+}
+state SUSPENDED
+`
+	if !strings.Contains(got, expected) {
+		t.Errorf("sub states should be composite states:\n%v", got)
+	}
+	if !strings.Contains(got, "ACTIVE --> SUSPENDED : SUSPEND\n") {
+		t.Errorf("transitions should be written:\n%v", got)
+	}
+}
+
+func TestPlantUMLMetadataNotes(t *testing.T) {
+	f, err := New("Job", [][3]string{
+		{"QUEUED", "RUNNING", "START"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetStateMeta("RUNNING", "owner", "scheduler"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetStateMeta("RUNNING", "retries", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetStateMeta("QUEUED", "owner", "api"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.SetStateMeta("MISSING", "owner", "api"); err != ErrStateNotFound {
+		t.Errorf("should errored state not found, got %v", err)
+	}
+	got := f.PlantUML(PlantUMLOptions{
+		Notes:         map[string]string{"QUEUED": "explicit"},
+		MetadataNotes: true,
+	})
+	expected := `note right of QUEUED : explicit
+note right of RUNNING
+  owner: scheduler
+  retries: 3
+end note
+`
+	if !strings.Contains(got, expected) {
+		t.Errorf("metadata should be written as notes:\n%v", got)
+	}
+
+	if err = f.Init("QUEUED"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f2 FSM
+	if err = json.Unmarshal(b, &f2); err != nil {
+		t.Fatal(err)
+	}
+	if f2.GetStateMeta("RUNNING")["retries"] != "3" {
+		t.Errorf("metadata should survive a round trip: %v", f2.GetStateMeta("RUNNING"))
+	}
+}
@@ -0,0 +1,121 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// pairPrefix starts the names of the pair states created by Product
+const pairPrefix = "PAIR"
+
+// Product builds the synchronous product of two fsm, its states are the pairs of their states
+// named PAIR_A, PAIR_B... in the order they are found
+// It also returns the states of a and b of every pair state
+// An action of both fsm moves both of them and it is not allowed when one of them cannot move,
+// an action of one fsm only moves that one
+// A pair state is final when both its states are final
+// When both fsm have an initial state, only the pairs reachable from the initial pair and from the current pair are kept,
+// otherwise every pair is kept
+// Sub states are flattened like in Determinize, the guards, hooks and timeouts are not kept
+func Product(a *FSM, b *FSM) (*FSM, map[string][2]string, error) {
+	actionsA, actionsB := actionSet(a), actionSet(b)
+	actions := make(map[string]bool)
+	for action := range actionsA {
+		actions[action] = true
+	}
+	for action := range actionsB {
+		actions[action] = true
+	}
+	var names []string
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+
+	p := NewFSM(fmt.Sprintf("%v x %v", a.Name, b.Name))
+	pairs := make(map[string][2]string)
+	byPair := make(map[[2]string]string)
+	next := namer(p, pairPrefix)
+	var queue [][2]string
+	add := func(pair [2]string) (string, error) {
+		if name, ok := byPair[pair]; ok {
+			return name, nil
+		}
+		name := next()
+		if err := p.AddState(name); err != nil {
+			return "", err
+		}
+		byPair[pair] = name
+		pairs[name] = pair
+		queue = append(queue, pair)
+		if a.final[pair[0]] && b.final[pair[1]] {
+			return name, p.AddFinal(name)
+		}
+		return name, nil
+	}
+
+	if a.initial != "" && b.initial != "" {
+		for _, pair := range [][2]string{{a.initial, b.initial}, {a.current, b.current}} {
+			if pair[0] == "" || pair[1] == "" {
+				continue
+			}
+			if _, err := add(pair); err != nil {
+				return nil, nil, err
+			}
+		}
+	} else {
+		for _, sa := range a.stateNames() {
+			for _, sb := range b.stateNames() {
+				if _, err := add([2]string{sa, sb}); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		pair := queue[0]
+		queue = queue[1:]
+		src := byPair[pair]
+		for _, action := range names {
+			targetsA := []string{pair[0]}
+			if actionsA[action] {
+				targetsA = a.targets(pair[0], action)
+			}
+			targetsB := []string{pair[1]}
+			if actionsB[action] {
+				targetsB = b.targets(pair[1], action)
+			}
+			for _, ta := range targetsA {
+				for _, tb := range targetsB {
+					des, err := add([2]string{ta, tb})
+					if err != nil {
+						return nil, nil, err
+					}
+					if err = p.AddTrans(src, des, action); err != nil {
+						return nil, nil, err
+					}
+				}
+			}
+		}
+	}
+
+	if a.initial != "" && b.initial != "" {
+		if err := p.Init(byPair[[2]string{a.initial, b.initial}]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if a.current != "" && b.current != "" {
+		p.reset(byPair[[2]string{a.current, b.current}])
+	}
+	return p, pairs, nil
+}
+
+// actionSet returns the actions of the transitions of the fsm
+func actionSet(f *FSM) map[string]bool {
+	actions := make(map[string]bool)
+	for _, adj := range f.adj {
+		actions[adj.Action] = true
+	}
+	return actions
+}
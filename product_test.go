@@ -0,0 +1,132 @@
+package fsm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProduct(t *testing.T) {
+	plan, err := New("Plan", [][3]string{
+		{"TRIAL", "BASIC", "UPGRATE"},
+		{"BASIC", "CANCELED", "CANCEL"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := New("Payment", [][3]string{
+		{"UNPAID", "PAID", "PAY"},
+		{"PAID", "REFUNDED", "CANCEL"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plan.AddFinal("CANCELED"); err != nil {
+		t.Fatal(err)
+	}
+	if err = payment.AddFinal("REFUNDED"); err != nil {
+		t.Fatal(err)
+	}
+	if err = plan.Init("TRIAL"); err != nil {
+		t.Fatal(err)
+	}
+	if err = payment.Init("UNPAID"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, pairs, err := Product(plan, payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Plan x Payment" {
+		t.Errorf("wrong name: %v", p.Name)
+	}
+	want := map[string][2]string{
+		"PAIR_A": {"TRIAL", "UNPAID"},
+		"PAIR_B": {"TRIAL", "PAID"},
+		"PAIR_C": {"BASIC", "UNPAID"},
+		"PAIR_D": {"BASIC", "PAID"},
+		"PAIR_E": {"CANCELED", "REFUNDED"},
+	}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("wrong pair states: %v", pairs)
+	}
+	if got := p.stateNames(); len(got) != len(want) {
+		t.Errorf("wrong states: %v", got)
+	}
+	if p.GetInitial() != "PAIR_A" || p.GetState() != "PAIR_A" {
+		t.Errorf("state should be PAIR_A, got %v", p.GetState())
+	}
+	if !p.IsFinal("PAIR_E") {
+		t.Errorf("wrong final states: %v", p.finalNames())
+	}
+	// CANCEL is shared, it needs both machines to move
+	if err = p.Fire("CANCEL", nil); !errors.Is(err, ErrExecNotAllowed) {
+		t.Errorf("should errored exec not allowed, got %v", err)
+	}
+	for _, action := range []string{"PAY", "UPGRATE", "CANCEL"} {
+		if err = p.Fire(action, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !p.Terminated() {
+		t.Errorf("product should be terminated, got %v", p.GetState())
+	}
+}
+
+func TestProductAllPairs(t *testing.T) {
+	a, err := New("A", [][3]string{{"X", "Y", "GO"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New("B", [][3]string{{"Z", "W", "GO"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, pairs, err := Product(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(p.states); got != 4 {
+		t.Errorf("wrong number of states: %v", got)
+	}
+	// without initial states every pair is kept, in the order of the sorted states
+	if pairs["PAIR_B"] != [2]string{"X", "Z"} || pairs["PAIR_C"] != [2]string{"Y", "W"} {
+		t.Errorf("wrong pair states: %v", pairs)
+	}
+	if _, ok := p.findTrans("PAIR_B", "PAIR_C", "GO"); !ok {
+		t.Errorf("PAIR_B should move to PAIR_C\n%v", p.GetTrans())
+	}
+	if len(p.adj) != 1 {
+		t.Errorf("wrong transitions:\n%v", p.GetTrans())
+	}
+}
+
+func TestProductNames(t *testing.T) {
+	long := strings.Repeat("A", 40)
+	a, err := New("A", [][3]string{{long, "PAIR_A", "GO"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New("B", [][3]string{{long, "PAIR_B", "GO"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Init(long); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Init(long); err != nil {
+		t.Fatal(err)
+	}
+	p, pairs, err := Product(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pairs["PAIR_A"] != [2]string{long, long} || pairs["PAIR_B"] != [2]string{"PAIR_A", "PAIR_B"} {
+		t.Errorf("wrong pair states: %v", pairs)
+	}
+	if err = p.Fire("GO", nil); err != nil || p.GetState() != "PAIR_B" {
+		t.Errorf("state should be PAIR_B, got %v %v", p.GetState(), err)
+	}
+}
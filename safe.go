@@ -0,0 +1,180 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SafeFSM wraps a FSM to make it safe for concurrent use.
+// Every method is atomic with respect to the others.
+// Hooks, guards and callbacks run while the lock is held,
+// they can use the wrapped FSM but must not call the SafeFSM.
+type SafeFSM struct {
+	mu  sync.RWMutex
+	fsm *FSM
+}
+
+// NewSafe wraps the given fsm, the fsm must not be used directly afterwards
+func NewSafe(f *FSM) *SafeFSM {
+	return &SafeFSM{fsm: f}
+}
+
+// Do calls fn with the wrapped fsm while holding the lock,
+// it allows running several operations atomically
+func (s *SafeFSM) Do(fn func(f *FSM) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.fsm)
+}
+
+// GetState gets the current state
+func (s *SafeFSM) GetState() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fsm.GetState()
+}
+
+// GetTrans returns the transitions string representation of fsm
+func (s *SafeFSM) GetTrans() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fsm.GetTrans()
+}
+
+// AddState adds a new state into the fsm
+func (s *SafeFSM) AddState(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.AddState(name)
+}
+
+// AddTrans adds a new transition between two states
+func (s *SafeFSM) AddTrans(src string, des string, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.AddTrans(src, des, name)
+}
+
+// AddGuard adds a named guard to an existing transition
+func (s *SafeFSM) AddGuard(src string, des string, action string, name string, g Guard) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.AddGuard(src, des, action, name, g)
+}
+
+// Init set the current state to the given state
+func (s *SafeFSM) Init(state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.Init(state)
+}
+
+// Exec moves the fsm to the given state using the given action
+func (s *SafeFSM) Exec(action string, des string, callback func(previous string, new string, action string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.Exec(action, des, callback)
+}
+
+// Fire executes the given action from the current state
+func (s *SafeFSM) Fire(action string, callback func(previous string, new string, action string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.Fire(action, callback)
+}
+
+// Terminated reports whether the current state is a final state
+func (s *SafeFSM) Terminated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fsm.Terminated()
+}
+
+func (s *SafeFSM) MarshalJSON() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fsm.MarshalJSON()
+}
+
+func (s *SafeFSM) UnmarshalJSON(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fsm == nil {
+		s.fsm = &FSM{}
+	}
+	return s.fsm.UnmarshalJSON(data)
+}
+
+// InitContext is like Init but it honors the context
+func (s *SafeFSM) InitContext(ctx context.Context, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.InitContext(ctx, state)
+}
+
+// ExecContext is like Exec but the hooks and the callback receive the context
+func (s *SafeFSM) ExecContext(ctx context.Context, action string, des string, callback ContextHook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.ExecContext(ctx, action, des, callback)
+}
+
+// FireContext is like Fire but the hooks and the callback receive the context
+func (s *SafeFSM) FireContext(ctx context.Context, action string, callback ContextHook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.FireContext(ctx, action, callback)
+}
+
+// EnableHistory makes the fsm record every executed transition
+func (s *SafeFSM) EnableHistory() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fsm.EnableHistory()
+}
+
+// History returns the recorded transitions, oldest first
+func (s *SafeFSM) History() []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fsm.History()
+}
+
+// AddTimeout fires the given action once the fsm spent the given duration in the given state
+func (s *SafeFSM) AddTimeout(state string, after time.Duration, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.AddTimeout(state, after, action)
+}
+
+// Pending returns the timers of the active path, earliest first
+func (s *SafeFSM) Pending() []Timer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fsm.Pending()
+}
+
+// Tick fires every timer due by now and returns how many fired
+func (s *SafeFSM) Tick(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fsm.Tick(ctx)
+}
+
+// RunTimers calls Tick at the given interval until the context is done or Tick fails,
+// skipped timers do not make Tick fail
+func (s *SafeFSM) RunTimers(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := s.Tick(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package fsm
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// run with go test -race
+func TestSafeConcurrentExec(t *testing.T) {
+	f, err := New("Transfer", [][3]string{
+		{"OPEN", "LOCKED", "LOCK"},
+		{"LOCKED", "OPEN", "UNLOCK"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Init("OPEN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSafe(f)
+
+	const workers = 100
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	locked := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Exec("LOCK", "LOCKED", nil) == nil {
+				mu.Lock()
+				locked++
+				mu.Unlock()
+			}
+			_ = s.GetState()
+			if _, err := json.Marshal(s); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if locked != 1 {
+		t.Errorf("only one goroutine should lock, got %v", locked)
+	}
+	if s.GetState() != "LOCKED" {
+		t.Errorf("state should be LOCKED")
+	}
+}
+
+func TestSafeConcurrentDefinition(t *testing.T) {
+	s := NewSafe(NewFSM("Concurrent"))
+	states := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+
+	var wg sync.WaitGroup
+	for _, state := range states {
+		wg.Add(1)
+		go func(state string) {
+			defer wg.Done()
+			if err := s.AddState(state); err != nil {
+				t.Error(err)
+			}
+		}(state)
+	}
+	wg.Wait()
+
+	for i := range states {
+		wg.Add(1)
+		go func(src string, des string) {
+			defer wg.Done()
+			if err := s.AddTrans(src, des, "NEXT"); err != nil {
+				t.Error(err)
+			}
+			_ = s.GetTrans()
+		}(states[i], states[(i+1)%len(states)])
+	}
+	wg.Wait()
+
+	if err := s.Init("A"); err != nil {
+		t.Fatal(err)
+	}
+	for range states {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Fire("NEXT", nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.GetState() != "A" {
+		t.Errorf("state should be A after a full cycle, got %v", s.GetState())
+	}
+}
+
+func TestSafeDo(t *testing.T) {
+	s := NewSafe(NewFSM("Do"))
+	err := s.Do(func(f *FSM) error {
+		if err := f.AddState("ON"); err != nil {
+			return err
+		}
+		if err := f.AddState("OFF"); err != nil {
+			return err
+		}
+		if err := f.AddTrans("OFF", "ON", "TURN_ON"); err != nil {
+			return err
+		}
+		return f.Init("OFF")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s2 SafeFSM
+	if err = json.Unmarshal(b, &s2); err != nil {
+		t.Fatal(err)
+	}
+	if err = s2.Fire("TURN_ON", nil); err != nil {
+		t.Fatal(err)
+	}
+	if s2.GetState() != "ON" {
+		t.Errorf("state should be ON")
+	}
+}
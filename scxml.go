@@ -0,0 +1,310 @@
+package fsm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+var ErrUnsupportedSCXML = errors.New("unsupported scxml")
+
+const scxmlNamespace = "http://www.w3.org/2005/07/scxml"
+
+// UnsupportedFeature is a SCXML feature that cannot be represented by a fsm
+type UnsupportedFeature struct {
+	// Line is the line of the document where the feature is used, 0 when writing
+	Line    int
+	Feature string
+}
+
+func (u UnsupportedFeature) String() string {
+	if u.Line == 0 {
+		return u.Feature
+	}
+	return fmt.Sprintf("line %v: %v", u.Line, u.Feature)
+}
+
+// SCXMLError lists every unsupported feature found in a SCXML document or a fsm
+// It wraps ErrUnsupportedSCXML
+type SCXMLError struct {
+	Features []UnsupportedFeature
+}
+
+func (e *SCXMLError) Error() string {
+	features := make([]string, 0, len(e.Features))
+	for _, f := range e.Features {
+		features = append(features, f.String())
+	}
+	return fmt.Sprintf("%v: %v", ErrUnsupportedSCXML, strings.Join(features, "; "))
+}
+
+func (e *SCXMLError) Unwrap() error {
+	return ErrUnsupportedSCXML
+}
+
+type scxmlTransition struct {
+	Event  string `xml:"event,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type scxmlFinal struct {
+	ID string `xml:"id,attr"`
+}
+
+type scxmlState struct {
+	ID          string            `xml:"id,attr"`
+	Transitions []scxmlTransition `xml:"transition"`
+	States      []scxmlState      `xml:"state"`
+	Finals      []scxmlFinal      `xml:"final"`
+}
+
+type scxmlDocument struct {
+	XMLName xml.Name     `xml:"scxml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Version string       `xml:"version,attr"`
+	Name    string       `xml:"name,attr,omitempty"`
+	Initial string       `xml:"initial,attr,omitempty"`
+	States  []scxmlState `xml:"state"`
+	Finals  []scxmlFinal `xml:"final"`
+}
+
+// SCXML returns the SCXML document of the fsm
+// Final states are written as <final> elements, so they cannot have sub states
+// or outgoing transitions, in that case it returns a *SCXMLError
+func (f *FSM) SCXML() ([]byte, error) {
+	var unsupported []UnsupportedFeature
+	children := f.children()
+	for _, name := range f.finalNames() {
+		if len(children[name]) > 0 {
+			unsupported = append(unsupported, UnsupportedFeature{Feature: fmt.Sprintf("final state %v with sub states", name)})
+		}
+	}
+	for _, adj := range f.adj {
+		if f.final[adj.From] {
+			unsupported = append(unsupported, UnsupportedFeature{Feature: fmt.Sprintf("transition %v out of final state %v", adj.Action, adj.From)})
+		}
+	}
+	if len(unsupported) > 0 {
+		return nil, &SCXMLError{Features: unsupported}
+	}
+
+	var build func(parent string) ([]scxmlState, []scxmlFinal)
+	build = func(parent string) ([]scxmlState, []scxmlFinal) {
+		var states []scxmlState
+		var finals []scxmlFinal
+		for _, name := range children[parent] {
+			if f.final[name] {
+				finals = append(finals, scxmlFinal{ID: name})
+				continue
+			}
+			s := scxmlState{ID: name}
+			for _, adj := range f.adj {
+				if adj.From == name {
+					s.Transitions = append(s.Transitions, scxmlTransition{Event: adj.Action, Target: adj.To})
+				}
+			}
+			s.States, s.Finals = build(name)
+			states = append(states, s)
+		}
+		return states, finals
+	}
+
+	doc := scxmlDocument{
+		Xmlns:   scxmlNamespace,
+		Version: "1.0",
+		Name:    f.Name,
+		Initial: f.initial,
+	}
+	doc.States, doc.Finals = build("")
+	b, err := xml.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+// ParseSCXML creates a fsm from a SCXML document
+// It supports <state> and <final> elements, nested states become sub states,
+// <transition> elements with a single event and a single target, and the initial attribute of <scxml>
+// Every other feature, like <parallel>, <history>, <onentry>, <datamodel>, cond or eventless transitions,
+// makes it return a *SCXMLError listing all of them with their line
+func ParseSCXML(r io.Reader) (*FSM, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	lineAt := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	type pendingTrans struct {
+		from   string
+		event  string
+		target string
+		line   int
+	}
+	var unsupported []UnsupportedFeature
+	var trans []pendingTrans
+	var stack []string // element names, the id of states is kept in ids
+	var ids []string
+	var firstState string
+	f := NewFSM("")
+	initial := ""
+	root := false
+
+	report := func(line int, format string, args ...interface{}) {
+		unsupported = append(unsupported, UnsupportedFeature{Line: line, Feature: fmt.Sprintf(format, args...)})
+	}
+	parentID := func() string {
+		for i := len(ids) - 1; i >= 0; i-- {
+			if ids[i] != "" {
+				return ids[i]
+			}
+		}
+		return ""
+	}
+
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line := lineAt(offset)
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			name := t.Name.Local
+			id := ""
+			attrs := make(map[string]string)
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+				attrs[a.Name.Local] = a.Value
+			}
+
+			switch {
+			case parent == "" && name == "scxml":
+				root = true
+				f.Name = attrs["name"]
+				initial = attrs["initial"]
+				if strings.Contains(initial, " ") {
+					report(line, "initial with several states %q", initial)
+				}
+				if dm, ok := attrs["datamodel"]; ok && dm != "null" {
+					report(line, "datamodel %q", dm)
+				}
+				for a := range attrs {
+					if a != "name" && a != "initial" && a != "version" && a != "datamodel" {
+						report(line, "attribute %v of <scxml>", a)
+					}
+				}
+			case parent == "":
+				return nil, fmt.Errorf("%w: line %v: root element is <%v>, expected <scxml>", ErrUnsupportedSCXML, line, name)
+			case (name == "state" || name == "final") && (parent == "scxml" || parent == "state"):
+				id = attrs["id"]
+				if id == "" {
+					report(line, "<%v> without id", name)
+					break
+				}
+				var err error
+				if p := parentID(); p != "" {
+					err = f.AddSubState(p, id)
+				} else {
+					err = f.AddState(id)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("line %v: state %v: %w", line, id, err)
+				}
+				if name == "final" {
+					if err = f.AddFinal(id); err != nil {
+						return nil, err
+					}
+				}
+				if firstState == "" && parentID() == "" {
+					firstState = id
+				}
+				if _, ok := attrs["initial"]; ok {
+					report(line, "initial attribute of <%v> %v", name, id)
+				}
+				for a := range attrs {
+					if a != "id" && a != "initial" {
+						report(line, "attribute %v of <%v> %v", a, name, id)
+					}
+				}
+			case name == "transition" && parent == "state":
+				event := attrs["event"]
+				target := attrs["target"]
+				switch {
+				case event == "":
+					report(line, "eventless transition in %v", parentID())
+				case strings.Contains(event, " "):
+					report(line, "transition with several events %q in %v", event, parentID())
+				case target == "":
+					report(line, "targetless transition %v in %v", event, parentID())
+				case strings.Contains(target, " "):
+					report(line, "transition %v with several targets %q in %v", event, target, parentID())
+				default:
+					trans = append(trans, pendingTrans{from: parentID(), event: event, target: target, line: line})
+				}
+				for a := range attrs {
+					if a != "event" && a != "target" {
+						report(line, "attribute %v of <transition> in %v", a, parentID())
+					}
+				}
+			default:
+				where := parentID()
+				if where == "" {
+					where = "<" + parent + ">"
+				}
+				report(line, "<%v> in %v", name, where)
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			stack = append(stack, name)
+			ids = append(ids, id)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			ids = ids[:len(ids)-1]
+		case xml.CharData:
+			if text := strings.TrimSpace(string(t)); text != "" && len(stack) > 0 {
+				report(lineAt(offset), "text %q in <%v>", text, stack[len(stack)-1])
+			}
+		}
+	}
+	if !root {
+		return nil, fmt.Errorf("%w: missing <scxml>", ErrUnsupportedSCXML)
+	}
+	if len(unsupported) > 0 {
+		return nil, &SCXMLError{Features: unsupported}
+	}
+
+	for _, t := range trans {
+		if err := f.AddTrans(t.from, t.target, t.event); err != nil {
+			return nil, fmt.Errorf("line %v: transition %v from %v to %v: %w", t.line, t.event, t.from, t.target, err)
+		}
+	}
+	if initial == "" {
+		initial = firstState
+	}
+	if initial != "" {
+		if err := f.Init(initial); err != nil {
+			return nil, fmt.Errorf("initial state %v: %w", initial, err)
+		}
+	}
+	return f, nil
+}
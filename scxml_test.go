@@ -0,0 +1,141 @@
+package fsm
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// assertSameDefinition compares the states, transitions, parents, initial and final states of two fsm
+func assertSameDefinition(t *testing.T, a *FSM, b *FSM) {
+	t.Helper()
+	describe := func(f *FSM) string {
+		var lines []string
+		for _, s := range f.stateNames() {
+			lines = append(lines, "state "+s+" parent "+f.GetParent(s))
+		}
+		for _, adj := range f.adj {
+			lines = append(lines, "trans "+adj.From+" "+adj.To+" "+adj.Action)
+		}
+		for _, s := range f.finalNames() {
+			lines = append(lines, "final "+s)
+		}
+		sort.Strings(lines)
+		return f.Name + "\ninitial " + f.GetInitial() + "\n" + strings.Join(lines, "\n")
+	}
+	if da, db := describe(a), describe(b); da != db {
+		t.Errorf("fsm differ:\n%v\n\n%v", da, db)
+	}
+}
+
+func TestSCXMLRoundTrip(t *testing.T) {
+	plan, err := NewPlan2("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := New("Job", [][3]string{
+		{"QUEUED", "RUNNING", "START"},
+		{"RUNNING", "DONE", "FINISH"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = job.AddFinal("DONE"); err != nil {
+		t.Fatal(err)
+	}
+	if err = job.Init("QUEUED"); err != nil {
+		t.Fatal(err)
+	}
+	tenant, err := NewTenant("TRIAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []*FSM{plan.State, job, tenant.State} {
+		b, err := f.SCXML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseSCXML(strings.NewReader(string(b)))
+		if err != nil {
+			t.Fatalf("%v: %v\n%v", f.Name, err, string(b))
+		}
+		assertSameDefinition(t, f, parsed)
+	}
+}
+
+func TestParseSCXML(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- designed in a visual editor -->
+<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0" name="Job" datamodel="null">
+  <state id="QUEUED">
+    <transition event="START" target="RUNNING"/>
+  </state>
+  <state id="RUNNING">
+    <transition event="FINISH" target="DONE"/>
+  </state>
+  <final id="DONE"/>
+</scxml>
+`
+	f, err := ParseSCXML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "Job" || f.GetState() != "QUEUED" || !f.IsFinal("DONE") {
+		t.Errorf("wrong fsm: %v %v", f.Name, f.GetState())
+	}
+	if err = f.Fire("START", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseSCXMLUnsupported(t *testing.T) {
+	doc := `<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0" initial="IDLE">
+  <datamodel><data id="count"/></datamodel>
+  <state id="IDLE">
+    <onentry><log expr="'idle'"/></onentry>
+    <transition event="GO" target="BUSY" cond="count &lt; 3"/>
+    <transition target="BUSY"/>
+  </state>
+  <parallel id="BUSY"/>
+</scxml>
+`
+	_, err := ParseSCXML(strings.NewReader(doc))
+	var serr *SCXMLError
+	if !errors.As(err, &serr) {
+		t.Fatalf("should errored unsupported scxml, got %v", err)
+	}
+	if !errors.Is(err, ErrUnsupportedSCXML) {
+		t.Errorf("should wrap ErrUnsupportedSCXML")
+	}
+	expected := []string{
+		"line 2: <datamodel> in <scxml>",
+		"line 4: <onentry> in IDLE",
+		"line 5: attribute cond of <transition> in IDLE",
+		"line 6: eventless transition in IDLE",
+		"line 8: <parallel> in <scxml>",
+	}
+	var got []string
+	for _, f := range serr.Features {
+		got = append(got, f.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong unsupported features:\n%v", strings.Join(got, "\n"))
+	}
+}
+
+func TestSCXMLUnsupportedFinal(t *testing.T) {
+	f, err := New("Job", [][3]string{
+		{"DONE", "QUEUED", "RETRY"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddFinal("DONE"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.SCXML(); !errors.Is(err, ErrUnsupportedSCXML) {
+		t.Errorf("should errored unsupported scxml, got %v", err)
+	}
+}
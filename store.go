@@ -0,0 +1,205 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrEntityNotFound = errors.New("entity not found")
+var ErrVersionConflict = errors.New("version conflict")
+
+// Store persists fsm by entity ID.
+// Every Save increments the version of the entity, a Save with
+// a version different from the stored one fails with ErrVersionConflict.
+// The version of an entity that was never saved is 0.
+type Store interface {
+	// Load restores the fsm of the entity into f and returns its version
+	// Guards and hooks already registered on f are kept
+	Load(ctx context.Context, id string, f *FSM) (int64, error)
+	// Save stores f as the given version of the entity and returns the new version
+	Save(ctx context.Context, id string, f *FSM, version int64) (int64, error)
+}
+
+// ExecStored loads the fsm of the entity into f, executes the action and saves it.
+// The save fails with ErrVersionConflict if the entity was saved in the meantime,
+// in that case the action can be safely retried.
+// The hooks and the callback are called once the save succeeded, when it fails f stays in the loaded state.
+func ExecStored(ctx context.Context, s Store, id string, f *FSM, action string, des string, callback ContextHook) error {
+	version, err := s.Load(ctx, id, f)
+	if err != nil {
+		return err
+	}
+	if err = f.check(ctx, action, des); err != nil {
+		return err
+	}
+	previous, at := f.current, f.now()
+	undo := f.restorer()
+	f.step(at, des)
+	f.record(ctx, at, previous, des, action)
+	_, err = s.Save(ctx, id, f, version)
+	undo()
+	if err != nil {
+		return err
+	}
+	f.move(ctx, at, action, des, callback)
+	return nil
+}
+
+// MemStore is a Store that keeps the fsm in memory
+type MemStore struct {
+	mu       sync.Mutex
+	entities map[string]storedFSM
+}
+
+type storedFSM struct {
+	Version int64           `json:"version"`
+	FSM     json.RawMessage `json:"fsm"`
+}
+
+// NewMemStore creates a pointer to a brand new MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{
+		entities: make(map[string]storedFSM),
+	}
+}
+
+func (m *MemStore) Load(ctx context.Context, id string, f *FSM) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	e, ok := m.entities[id]
+	m.mu.Unlock()
+	if !ok {
+		return 0, ErrEntityNotFound
+	}
+	if err := json.Unmarshal(e.FSM, f); err != nil {
+		return 0, err
+	}
+	return e.Version, nil
+}
+
+func (m *MemStore) Save(ctx context.Context, id string, f *FSM, version int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entities[id].Version != version {
+		return 0, ErrVersionConflict
+	}
+	m.entities[id] = storedFSM{Version: version + 1, FSM: b}
+	return version + 1, nil
+}
+
+// FileStore is a Store that keeps every fsm in its own JSON file inside a directory.
+// Saves are atomic: the file is written aside and then renamed.
+// Version checks only hold between users of the same FileStore value.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileStore creates a pointer to a brand new FileStore, it creates dir if it does not exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *FileStore) read(id string) (storedFSM, error) {
+	var e storedFSM
+	p, err := s.path(id)
+	if err != nil {
+		return e, err
+	}
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return e, ErrEntityNotFound
+	}
+	if err != nil {
+		return e, err
+	}
+	err = json.Unmarshal(b, &e)
+	return e, err
+}
+
+func (s *FileStore) Load(ctx context.Context, id string, f *FSM) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	e, err := s.read(id)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if err = json.Unmarshal(e.FSM, f); err != nil {
+		return 0, err
+	}
+	return e.Version, nil
+}
+
+func (s *FileStore) Save(ctx context.Context, id string, f *FSM, version int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	p, err := s.path(id)
+	if err != nil {
+		return 0, err
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, err := s.read(id)
+	if err != nil && err != ErrEntityNotFound {
+		return 0, err
+	}
+	if e.Version != version {
+		return 0, ErrVersionConflict
+	}
+	b, err = json.Marshal(storedFSM{Version: version + 1, FSM: b})
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := ioutil.TempFile(s.dir, id+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err = os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	return version + 1, nil
+}
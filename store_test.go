@@ -0,0 +1,141 @@
+package fsm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	_, err := s.Load(ctx, "ACC1", NewFSM("Account"))
+	if err != ErrEntityNotFound {
+		t.Errorf("should errored entity not found, got %v", err)
+	}
+
+	a, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := s.Save(ctx, "ACC1", a.State, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Errorf("version should be 1, got %v", version)
+	}
+	_, err = s.Save(ctx, "ACC1", a.State, 0)
+	if err != ErrVersionConflict {
+		t.Errorf("should errored version conflict, got %v", err)
+	}
+
+	f := NewFSM("Account")
+	guarded := false
+	entered := false
+	err = ExecStored(ctx, s, "ACC1", f, "UPGRATE", "PREMIUM", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.AddGuard("PREMIUM", "BASIC", "DOWNGRATE", "REVIEWED", func(pre string, cur string, action string) bool {
+		guarded = true
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.OnEnter("BASIC", func(pre string, cur string, action string) {
+		entered = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ExecStored(ctx, s, "ACC1", f, "DOWNGRATE", "BASIC", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !guarded || !entered {
+		t.Errorf("guards and hooks should be kept on load")
+	}
+
+	loaded := NewFSM("Account")
+	version, err = s.Load(ctx, "ACC1", loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Errorf("version should be 3, got %v", version)
+	}
+	if loaded.GetState() != "BASIC" {
+		t.Errorf("state should be BASIC, got %v", loaded.GetState())
+	}
+
+	err = ExecStored(ctx, s, "ACC1", loaded, "DOWNGRATE", "BASIC", nil)
+	if err != ErrExecNotAllowed {
+		t.Errorf("should errored exec not allowed, got %v", err)
+	}
+}
+
+// conflictStore is a Store saved by someone else between every Load and Save
+type conflictStore struct {
+	Store
+}
+
+func (s conflictStore) Save(ctx context.Context, id string, f *FSM, version int64) (int64, error) {
+	return 0, ErrVersionConflict
+}
+
+func TestExecStoredConflict(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemStore()
+	a, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Save(ctx, "ACC1", a.State, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFSM("Account")
+	called := false
+	err = ExecStored(ctx, conflictStore{s}, "ACC1", f, "UPGRATE", "PREMIUM", func(ctx context.Context, pre string, cur string, action string) {
+		called = true
+	})
+	if err != ErrVersionConflict {
+		t.Errorf("should errored version conflict, got %v", err)
+	}
+	if called {
+		t.Errorf("callback should not be called when the save fails")
+	}
+	if f.GetState() != "BASIC" {
+		t.Errorf("state should be BASIC, got %v", f.GetState())
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	testStore(t, NewMemStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+
+	a, err := NewPlan("BASIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Save(context.Background(), "../ESCAPE", a.State, 0)
+	if err != ErrInvalidName {
+		t.Errorf("should errored invalid name, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the current time to the fsm, it can be replaced to control time in tests
+type Clock interface {
+	Now() time.Time
+}
+
+// ManualClock is a Clock that only moves when told to
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a pointer to a brand new ManualClock set to the given time
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the time of the clock
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// timeout fires Action after the fsm spent After in State
+type timeout struct {
+	State  string
+	After  time.Duration
+	Action string
+}
+
+// Timer is a pending timeout, Action fires at Deadline unless the fsm leaves State before
+type Timer struct {
+	State    string
+	Action   string
+	Deadline time.Time
+}
+
+// SetClock sets the clock used by the fsm, by default it uses the system clock
+func (f *FSM) SetClock(c Clock) {
+	f.clock = c
+}
+
+func (f *FSM) now() time.Time {
+	if f.clock == nil {
+		return time.Now()
+	}
+	return f.clock.Now()
+}
+
+// AddTimeout fires the given action once the fsm spent the given duration in the given state
+// It validates state exists, action name, and the duration is positive prior to add it
+// It validates the timeout is unique for the state and action
+// Timeouts on a parent state apply while the fsm is in any of its descendants
+// The action fires from Tick, its destination is resolved like Fire does
+// and it must leave the state, or leave it and enter it again
+func (f *FSM) AddTimeout(state string, after time.Duration, action string) error {
+	if ok := f.states[state]; !ok {
+		return ErrStateNotFound
+	}
+	if !isValidName(action) {
+		return ErrInvalidName
+	}
+	if after <= 0 {
+		return ErrInvalidTimeout
+	}
+	for _, t := range f.timeouts {
+		if t.State == state && t.Action == action {
+			return ErrTimeoutAlExists
+		}
+	}
+	f.timeouts = append(f.timeouts, timeout{State: state, After: after, Action: action})
+	return nil
+}
+
+// Pending returns the timers of the active path, earliest first
+func (f *FSM) Pending() []Timer {
+	var timers []Timer
+	// a terminated fsm does not leave its state
+	if f.canLeave() != nil {
+		return nil
+	}
+	for _, t := range f.timeouts {
+		entered, ok := f.entered[t.State]
+		if !ok {
+			continue
+		}
+		timers = append(timers, Timer{
+			State:    t.State,
+			Action:   t.Action,
+			Deadline: entered.Add(t.After),
+		})
+	}
+	sort.SliceStable(timers, func(i, j int) bool {
+		return timers[i].Deadline.Before(timers[j].Deadline)
+	})
+	return timers
+}
+
+// Tick fires every timer due by now, in deadline order, and returns how many fired
+// The states entered by a timer are entered at its deadline, so after a long pause
+// chained timeouts fire as they would have on time
+// A timer rejected by a guard, or whose action has no single transition from the state,
+// is skipped, it stays due and the next Tick tries it again
+func (f *FSM) Tick(ctx context.Context) (int, error) {
+	fired := 0
+	now := f.now()
+	skipped := make(map[[2]string]bool)
+	for {
+		var t Timer
+		due := false
+		for _, timer := range f.Pending() {
+			if timer.Deadline.After(now) {
+				break
+			}
+			if !skipped[[2]string{timer.State, timer.Action}] {
+				t, due = timer, true
+				break
+			}
+		}
+		if !due {
+			return fired, nil
+		}
+		des, err := f.resolve(t.Action)
+		if err == nil {
+			err = f.check(ctx, t.Action, des)
+		}
+		if errors.Is(err, ErrGuardRejected) || errors.Is(err, ErrExecNotAllowed) || errors.Is(err, ErrAmbiguousTrans) {
+			skipped[[2]string{t.State, t.Action}] = true
+			continue
+		}
+		if err != nil {
+			return fired, err
+		}
+		// a timer that does not leave its state would fire forever
+		if exit, _ := f.exitEnter(f.current, des); !contains(exit, t.State) {
+			return fired, ErrInvalidTimeout
+		}
+		f.move(ctx, t.Deadline, t.Action, des, nil)
+		fired++
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimeouts(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+
+	if err := f.AddTimeout("TRIAL", time.Hour, "EXPIRE"); err != ErrTimeoutAlExists {
+		t.Errorf("should errored timeout already exists, got %v", err)
+	}
+	if err := f.AddTimeout("TRIAL", 0, "UPGRATE"); err != ErrInvalidTimeout {
+		t.Errorf("should errored invalid timeout, got %v", err)
+	}
+
+	pending := f.Pending()
+	if len(pending) != 1 || pending[0].Action != "EXPIRE" || !pending[0].Deadline.Equal(start.Add(14*24*time.Hour)) {
+		t.Errorf("wrong pending timers: %+v", pending)
+	}
+
+	clock.Advance(13 * 24 * time.Hour)
+	fired, err := f.Tick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired != 0 || f.GetState() != "TRIAL" {
+		t.Errorf("nothing should fire before the deadline")
+	}
+
+	clock.Advance(24 * time.Hour)
+	fired, err = f.Tick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired != 1 || f.GetState() != "EXPIRED" {
+		t.Errorf("EXPIRE should fire, got %v fired and state %v", fired, f.GetState())
+	}
+}
+
+func TestTimeoutsCatchUp(t *testing.T) {
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+	f.EnableHistory()
+
+	clock.Advance(60 * 24 * time.Hour)
+	fired, err := f.Tick(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired != 2 || f.GetState() != "DELETED" {
+		t.Errorf("both timeouts should fire, got %v fired and state %v", fired, f.GetState())
+	}
+	history := f.History()
+	if len(history) != 2 || !history[1].At.Equal(start.Add(44*24*time.Hour)) {
+		t.Errorf("DELETE should fire at its deadline: %+v", history)
+	}
+}
+
+func TestTimeoutsLeftState(t *testing.T) {
+	clock := NewManualClock(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC))
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+	if err := f.Exec("UPGRATE", "BASIC", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Pending()) != 0 {
+		t.Errorf("timers should be canceled when leaving the state")
+	}
+	clock.Advance(90 * 24 * time.Hour)
+	fired, err := f.Tick(context.Background())
+	if err != nil || fired != 0 {
+		t.Errorf("nothing should fire, got %v %v", fired, err)
+	}
+}
+
+func TestTimeoutsGuardRejected(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+	if err = f.AddTrans("TRIAL", "DELETED", "DELETE"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddTimeout("TRIAL", 7*24*time.Hour, "DELETE"); err != nil {
+		t.Fatal(err)
+	}
+	err = f.AddGuard("TRIAL", "DELETED", "DELETE", "INACTIVE", func(pre string, cur string, action string) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// DELETE is rejected, EXPIRE is due later and fires
+	clock.Advance(20 * 24 * time.Hour)
+	fired, err := f.Tick(ctx)
+	if err != nil {
+		t.Errorf("a rejected timer should be skipped, got %v", err)
+	}
+	if fired != 1 || f.GetState() != "EXPIRED" {
+		t.Errorf("EXPIRE should fire, got %v fired and state %v", fired, f.GetState())
+	}
+}
+
+func TestTimeoutsMisconfigured(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+	// DELETE has no transition from TRIAL, UPGRATE leads to BASIC and to PREMIUM
+	if err = f.AddTimeout("TRIAL", time.Hour, "DELETE"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddTimeout("TRIAL", 2*time.Hour, "UPGRATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(15 * 24 * time.Hour)
+	fired, err := f.Tick(ctx)
+	if err != nil {
+		t.Errorf("a misconfigured timer should be skipped, got %v", err)
+	}
+	if fired != 1 || f.GetState() != "EXPIRED" {
+		t.Errorf("EXPIRE should fire, got %v fired and state %v", fired, f.GetState())
+	}
+}
+
+func TestTimeoutsGuardRetried(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+	allowed := false
+	err = f.AddGuard("TRIAL", "EXPIRED", "EXPIRE", "ALLOWED", func(pre string, cur string, action string) bool {
+		return allowed
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(15 * 24 * time.Hour)
+	fired, err := f.Tick(ctx)
+	if err != nil || fired != 0 || f.GetState() != "TRIAL" {
+		t.Errorf("EXPIRE should be skipped, got %v fired, state %v and %v", fired, f.GetState(), err)
+	}
+	allowed = true
+	fired, err = f.Tick(ctx)
+	if err != nil || fired != 1 || f.GetState() != "EXPIRED" {
+		t.Errorf("EXPIRE should fire once allowed, got %v fired, state %v and %v", fired, f.GetState(), err)
+	}
+}
+
+func TestTimeoutsAfterReplay(t *testing.T) {
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+
+	expired := start.Add(2 * 24 * time.Hour)
+	err = Replay(f, []Event{{Seq: 1, Action: "EXPIRE", From: "TRIAL", To: "EXPIRED", At: expired}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := f.Pending()
+	if len(pending) != 1 || pending[0].Action != "DELETE" || !pending[0].Deadline.Equal(expired.Add(30*24*time.Hour)) {
+		t.Errorf("wrong pending timers: %+v", pending)
+	}
+
+	clock.Advance(40 * 24 * time.Hour)
+	fired, err := f.Tick(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired != 1 || f.GetState() != "DELETED" {
+		t.Errorf("DELETE should fire, got %v fired and state %v", fired, f.GetState())
+	}
+}
+
+func TestTimeoutsMarshalUnmarshal(t *testing.T) {
+	start := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	trial, err := NewTrial(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := trial.State
+	clock.Advance(10 * 24 * time.Hour)
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f2 FSM
+	if err = json.Unmarshal(b, &f2); err != nil {
+		t.Fatal(err)
+	}
+	f2.SetClock(clock)
+	pending := f2.Pending()
+	if len(pending) != 1 || !pending[0].Deadline.Equal(start.Add(14*24*time.Hour)) {
+		t.Errorf("pending timers should survive a restart: %+v", pending)
+	}
+	clock.Advance(4 * 24 * time.Hour)
+	if _, err = f2.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if f2.GetState() != "EXPIRED" {
+		t.Errorf("state should be EXPIRED, got %v", f2.GetState())
+	}
+}
+
+func TestSafeRunTimers(t *testing.T) {
+	f, err := New("Session", [][3]string{
+		{"ACTIVE", "IDLE", "TIMEOUT"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.AddTimeout("ACTIVE", time.Millisecond, "TIMEOUT"); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Init("ACTIVE"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSafe(f)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		for s.GetState() != "IDLE" {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if err = s.RunTimers(ctx, time.Millisecond); err != context.Canceled {
+		t.Errorf("should stop when canceled, got %v", err)
+	}
+	if s.GetState() != "IDLE" {
+		t.Errorf("TIMEOUT should fire")
+	}
+}
+
+func TestTimeoutMustLeaveState(t *testing.T) {
+	clock := NewManualClock(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC))
+	f := NewFSM("Nested")
+	f.SetClock(clock)
+	if err := f.AddState("ACTIVE"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddSubState("ACTIVE", "TRIAL"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddSubState("ACTIVE", "BASIC"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddTrans("TRIAL", "BASIC", "UPGRATE"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddTimeout("ACTIVE", time.Hour, "UPGRATE"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Init("TRIAL"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Hour)
+	if _, err := f.Tick(context.Background()); err != ErrInvalidTimeout {
+		t.Errorf("should errored invalid timeout, got %v", err)
+	}
+	if f.GetState() != "TRIAL" {
+		t.Errorf("state should not change")
+	}
+}
@@ -0,0 +1,239 @@
+package fsm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseTOML creates a fsm from a TOML-like definition, with the same content as ParseYAML
+//
+//	name = "SAAS Account State"
+//	initial = "TRIAL"
+//	final = ["CANCELED"]
+//
+//	[metadata]
+//	owner = "billing"
+//
+//	[states.TRIAL.metadata]
+//	days = "14"
+//	[states.BASIC]
+//	[states.CANCELED]
+//
+//	[[transitions]]
+//	from = "TRIAL"
+//	to = "BASIC"
+//	action = "UPGRATE"
+//
+// Only the subset of TOML needed by definitions is supported: tables, arrays of tables,
+// and keys with strings, bare values or single line arrays of them.
+// Errors report the line.
+func ParseTOML(r io.Reader) (*FSM, error) {
+	root, err := parseTOML(r)
+	if err != nil {
+		return nil, err
+	}
+	return build(root)
+}
+
+func parseTOML(r io.Reader) (*node, error) {
+	root := &node{kind: mapNode, line: 1}
+	current := root
+	tables := make(map[*node]bool)
+	scanner := bufio.NewScanner(r)
+	no := 0
+	for scanner.Scan() {
+		no++
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if no == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(text, "[["):
+			if !strings.HasSuffix(text, "]]") {
+				return nil, defError(no, "%w: unterminated array of tables", ErrInvalidDefinition)
+			}
+			path, err := tomlPath(text[2:len(text)-2], no)
+			if err != nil {
+				return nil, err
+			}
+			parent, err := tomlTable(root, path[:len(path)-1], no)
+			if err != nil {
+				return nil, err
+			}
+			key := path[len(path)-1]
+			arr := parent.get(key)
+			if arr == nil {
+				arr = &node{kind: seqNode, line: no}
+				if err = parent.set(key, arr); err != nil {
+					return nil, defError(no, "%w", err)
+				}
+			}
+			if arr.kind != seqNode {
+				return nil, defError(no, "%w: %v is not an array of tables", ErrInvalidDefinition, key)
+			}
+			current = &node{kind: mapNode, line: no}
+			arr.items = append(arr.items, current)
+		case strings.HasPrefix(text, "["):
+			if !strings.HasSuffix(text, "]") {
+				return nil, defError(no, "%w: unterminated table", ErrInvalidDefinition)
+			}
+			path, err := tomlPath(text[1:len(text)-1], no)
+			if err != nil {
+				return nil, err
+			}
+			current, err = tomlTable(root, path, no)
+			if err != nil {
+				return nil, err
+			}
+			if tables[current] {
+				return nil, defError(no, "%w: table %v defined twice", ErrInvalidDefinition, strings.Join(path, "."))
+			}
+			tables[current] = true
+		default:
+			i := strings.Index(text, "=")
+			if i < 0 {
+				return nil, defError(no, "%w: expected key = value, got %v", ErrInvalidDefinition, text)
+			}
+			key, err := unquote(strings.TrimSpace(text[:i]))
+			if err != nil || key == "" {
+				return nil, defError(no, "%w: invalid key %v", ErrInvalidDefinition, text[:i])
+			}
+			val, err := parseTOMLValue(strings.TrimSpace(text[i+1:]), no)
+			if err != nil {
+				return nil, err
+			}
+			if err = current.set(key, val); err != nil {
+				return nil, defError(no, "%w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// tomlPath splits a dotted table name, parts can be quoted
+func tomlPath(text string, line int) ([]string, error) {
+	var path []string
+	for _, part := range strings.Split(text, ".") {
+		key, err := unquote(strings.TrimSpace(part))
+		if err != nil || key == "" {
+			return nil, defError(line, "%w: invalid table name %v", ErrInvalidDefinition, text)
+		}
+		path = append(path, key)
+	}
+	return path, nil
+}
+
+// tomlTable returns the table at the path, creating the missing ones
+// A path through an array of tables continues from its last table
+func tomlTable(root *node, path []string, line int) (*node, error) {
+	n := root
+	for _, key := range path {
+		next := n.get(key)
+		if next == nil {
+			next = &node{kind: mapNode, line: line}
+			if err := n.set(key, next); err != nil {
+				return nil, defError(line, "%w", err)
+			}
+		}
+		if next.kind == seqNode && len(next.items) > 0 {
+			next = next.items[len(next.items)-1]
+		}
+		if next.kind != mapNode {
+			return nil, defError(line, "%w: %v is not a table", ErrInvalidDefinition, key)
+		}
+		n = next
+	}
+	return n, nil
+}
+
+func parseTOMLValue(text string, line int) (*node, error) {
+	if strings.HasPrefix(text, "[") {
+		if !strings.HasSuffix(text, "]") {
+			return nil, defError(line, "%w: arrays must be on a single line", ErrInvalidDefinition)
+		}
+		n := &node{kind: seqNode, line: line}
+		for _, part := range splitFlow(text[1 : len(text)-1]) {
+			if part == "" {
+				continue
+			}
+			item, err := parseTOMLValue(part, line)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		return n, nil
+	}
+	if text == "" || strings.ContainsAny(text[:1], "{]") {
+		return nil, defError(line, "%w: unsupported value %v", ErrInvalidDefinition, text)
+	}
+	value, err := unquote(text)
+	if err != nil {
+		return nil, defError(line, "%w: invalid value %v", ErrInvalidDefinition, text)
+	}
+	return &node{kind: scalarNode, line: line, value: value}, nil
+}
+
+// TOML returns the TOML-like definition of the fsm, ParseTOML reads it back
+func (f *FSM) TOML() string {
+	builder := strings.Builder{}
+	if f.Name != "" {
+		builder.WriteString(fmt.Sprintf("name = %v\n", strconv.Quote(f.Name)))
+	}
+	if f.initial != "" {
+		builder.WriteString(fmt.Sprintf("initial = %v\n", strconv.Quote(f.initial)))
+	}
+	if final := f.finalNames(); len(final) > 0 {
+		builder.WriteString(fmt.Sprintf("final = %v\n", tomlArray(final)))
+	}
+	writeTOMLMeta(&builder, f.meta, "metadata")
+	for _, name := range f.stateNames() {
+		builder.WriteString(fmt.Sprintf("\n[states.%v]\n", name))
+		if parent := f.parents[name]; parent != "" {
+			builder.WriteString(fmt.Sprintf("parent = %v\n", strconv.Quote(parent)))
+		}
+		writeTOMLMeta(&builder, f.stateMeta[name], "states."+name+".metadata")
+	}
+	for _, adj := range f.adj {
+		builder.WriteString(fmt.Sprintf("\n[[transitions]]\nfrom = %v\nto = %v\naction = %v\n",
+			strconv.Quote(adj.From), strconv.Quote(adj.To), strconv.Quote(adj.Action)))
+	}
+	for _, t := range f.timeouts {
+		builder.WriteString(fmt.Sprintf("\n[[timeouts]]\nstate = %v\nafter = %v\naction = %v\n",
+			strconv.Quote(t.State), strconv.Quote(t.After.String()), strconv.Quote(t.Action)))
+	}
+	return builder.String()
+}
+
+func tomlArray(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func writeTOMLMeta(builder *strings.Builder, meta map[string]string, table string) {
+	if len(meta) == 0 {
+		return
+	}
+	builder.WriteString(fmt.Sprintf("\n[%v]\n", table))
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		builder.WriteString(fmt.Sprintf("%v = %v\n", strconv.Quote(k), strconv.Quote(meta[k])))
+	}
+}
@@ -0,0 +1,379 @@
+package fsm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseYAML creates a fsm from a YAML definition
+//
+//	name: SAAS Account State
+//	initial: TRIAL
+//	final: [CANCELED]
+//	metadata:
+//	  owner: billing
+//	states:
+//	  TRIAL:
+//	    metadata:
+//	      days: "14"
+//	  BASIC:
+//	  CANCELED:
+//	transitions:
+//	  - {from: TRIAL, to: BASIC, action: UPGRATE}
+//	  - [TRIAL, CANCELED, CANCEL]
+//	timeouts:
+//	  - {state: TRIAL, after: 336h, action: CANCEL}
+//
+// States can also be a list of names, and a state can have a parent.
+// Only the subset of YAML needed by definitions is supported: block mappings and lists,
+// flow lists and mappings of values, plain and quoted values, and comments.
+// Errors report the line.
+func ParseYAML(r io.Reader) (*FSM, error) {
+	root, err := parseYAML(r)
+	if err != nil {
+		return nil, err
+	}
+	return build(root)
+}
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func parseYAML(r io.Reader) (*node, error) {
+	p := &yamlParser{}
+	scanner := bufio.NewScanner(r)
+	no := 0
+	for scanner.Scan() {
+		no++
+		raw := scanner.Text()
+		if no == 1 {
+			raw = strings.TrimPrefix(raw, "\ufeff")
+		}
+		text := strings.TrimRight(stripComment(raw), " \t")
+		content := strings.TrimLeft(text, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, defError(no, "%w: tabs are not allowed in indentation", ErrInvalidDefinition)
+		}
+		p.lines = append(p.lines, yamlLine{no: no, indent: len(text) - len(content), text: content})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return &node{kind: nullNode, line: 1}, nil
+	}
+	n, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		return nil, defError(l.no, "%w: unexpected indentation", ErrInvalidDefinition)
+	}
+	return n, nil
+}
+
+// stripComment removes a # comment that is not inside quotes
+func stripComment(s string) string {
+	var quote rune
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or the list starting at the current line
+func (p *yamlParser) block(indent int) (*node, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (*node, error) {
+	n := &node{kind: mapNode, line: p.lines[p.pos].no}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && isSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, defError(l.no, "%w: unexpected indentation", ErrInvalidDefinition)
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, defError(l.no, "%w: expected key: value, got %v", ErrInvalidDefinition, l.text)
+		}
+		p.pos++
+		var val *node
+		var err error
+		switch {
+		case rest != "":
+			val, err = parseFlow(rest, l.no)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			val, err = p.block(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSeqItem(p.lines[p.pos].text):
+			val, err = p.sequence(indent)
+		default:
+			val = &node{kind: nullNode, line: l.no}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = n.set(key, val); err != nil {
+			return nil, defError(l.no, "%w", err)
+		}
+	}
+	return n, nil
+}
+
+func (p *yamlParser) sequence(indent int) (*node, error) {
+	n := &node{kind: seqNode, line: p.lines[p.pos].no}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && !isSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, defError(l.no, "%w: unexpected indentation", ErrInvalidDefinition)
+		}
+		content := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		var item *node
+		var err error
+		switch {
+		case content == "":
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err = p.block(p.lines[p.pos].indent)
+			} else {
+				item = &node{kind: nullNode, line: l.no}
+			}
+		case isSeqItem(content):
+			return nil, defError(l.no, "%w: nested lists must be on their own lines", ErrInvalidDefinition)
+		default:
+			if _, _, ok := splitKey(content); ok {
+				// a mapping starting on the line of the item, its keys are aligned with the first one
+				p.lines[p.pos] = yamlLine{no: l.no, indent: l.indent + len(l.text) - len(content), text: content}
+				item, err = p.mapping(p.lines[p.pos].indent)
+			} else {
+				p.pos++
+				item, err = parseFlow(content, l.no)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+	}
+	return n, nil
+}
+
+// splitKey splits a key: value line, the key can be quoted
+func splitKey(text string) (string, string, bool) {
+	if text == "" || strings.ContainsRune("[{", rune(text[0])) {
+		return "", "", false
+	}
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		start = end + 2
+	}
+	for i := start; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			key, err := unquote(strings.TrimSpace(text[:i]))
+			if err != nil || key == "" {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseFlow parses a value, a flow list [a, b] or a flow mapping {a: b}
+func parseFlow(text string, line int) (*node, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, defError(line, "%w: unterminated list", ErrInvalidDefinition)
+		}
+		n := &node{kind: seqNode, line: line}
+		for _, part := range splitFlow(text[1 : len(text)-1]) {
+			item, err := parseScalar(part, line)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		return n, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, defError(line, "%w: unterminated mapping", ErrInvalidDefinition)
+		}
+		n := &node{kind: mapNode, line: line}
+		for _, part := range splitFlow(text[1 : len(text)-1]) {
+			key, rest, ok := splitKey(part)
+			if !ok {
+				return nil, defError(line, "%w: expected key: value, got %v", ErrInvalidDefinition, part)
+			}
+			val, err := parseScalar(rest, line)
+			if err != nil {
+				return nil, err
+			}
+			if err = n.set(key, val); err != nil {
+				return nil, defError(line, "%w", err)
+			}
+		}
+		return n, nil
+	}
+	return parseScalar(text, line)
+}
+
+// splitFlow splits the content of a flow list or mapping on the commas outside quotes
+func splitFlow(s string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+func parseScalar(text string, line int) (*node, error) {
+	if text == "" || text == "~" || text == "null" {
+		return &node{kind: nullNode, line: line}, nil
+	}
+	if strings.ContainsAny(text[:1], "[]{}&*!|>%@`") {
+		return nil, defError(line, "%w: unsupported value %v", ErrInvalidDefinition, text)
+	}
+	value, err := unquote(text)
+	if err != nil {
+		return nil, defError(line, "%w: invalid value %v", ErrInvalidDefinition, text)
+	}
+	return &node{kind: scalarNode, line: line, value: value}, nil
+}
+
+func unquote(text string) (string, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		return strconv.Unquote(text)
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return "", ErrInvalidDefinition
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	}
+	return text, nil
+}
+
+// yamlQuote quotes the value when it is not a plain YAML value
+func yamlQuote(s string) string {
+	if s == "" || s == "~" || s == "null" || s == "true" || s == "false" ||
+		strings.TrimSpace(s) != s ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.ContainsAny(s, "\n\t\"'") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return strconv.Quote(s)
+	}
+	// numbers, dates and times are not strings for other YAML parsers
+	if strings.ContainsAny(s[:1], "0123456789.+") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// YAML returns the YAML definition of the fsm, ParseYAML reads it back
+func (f *FSM) YAML() string {
+	builder := strings.Builder{}
+	if f.Name != "" {
+		builder.WriteString(fmt.Sprintf("name: %v\n", yamlQuote(f.Name)))
+	}
+	if f.initial != "" {
+		builder.WriteString(fmt.Sprintf("initial: %v\n", f.initial))
+	}
+	if final := f.finalNames(); len(final) > 0 {
+		builder.WriteString(fmt.Sprintf("final: [%v]\n", strings.Join(final, ", ")))
+	}
+	writeYAMLMeta(&builder, f.meta, "")
+	builder.WriteString("states:\n")
+	for _, name := range f.stateNames() {
+		builder.WriteString(fmt.Sprintf("  %v:\n", name))
+		if parent := f.parents[name]; parent != "" {
+			builder.WriteString(fmt.Sprintf("    parent: %v\n", parent))
+		}
+		writeYAMLMeta(&builder, f.stateMeta[name], "    ")
+	}
+	if len(f.adj) > 0 {
+		builder.WriteString("transitions:\n")
+		for _, adj := range f.adj {
+			builder.WriteString(fmt.Sprintf("  - {from: %v, to: %v, action: %v}\n", adj.From, adj.To, adj.Action))
+		}
+	}
+	if len(f.timeouts) > 0 {
+		builder.WriteString("timeouts:\n")
+		for _, t := range f.timeouts {
+			builder.WriteString(fmt.Sprintf("  - {state: %v, after: %v, action: %v}\n", t.State, t.After, t.Action))
+		}
+	}
+	return builder.String()
+}
+
+func writeYAMLMeta(builder *strings.Builder, meta map[string]string, indent string) {
+	if len(meta) == 0 {
+		return
+	}
+	builder.WriteString(indent + "metadata:\n")
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		builder.WriteString(fmt.Sprintf("%v  %v: %v\n", indent, yamlQuote(k), yamlQuote(meta[k])))
+	}
+}